@@ -5,10 +5,18 @@ import (
 )
 
 type Config struct {
-	LoggerConfig   LoggerConfig
-	TelegramConfig TelegramConfig
-	PostgresConfig PostgresConfig
-	MarkovConfig   MarkovConfig
+	LoggerConfig      LoggerConfig
+	TelegramConfig    TelegramConfig
+	PostgresConfig    PostgresConfig
+	MarkovConfig      MarkovConfig
+	RateLimitConfig   RateLimitConfig
+	MetricsConfig     MetricsConfig
+	TranscriberConfig TranscriberConfig
+	SchedulerConfig   SchedulerConfig
+	RetentionConfig   RetentionConfig
+	IngestConfig      IngestConfig
+	InlineConfig      InlineConfig
+	RuleConfig        RuleConfig
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+type (
+	IngestConfig struct {
+		// Messages are written in batches of up to BatchSize, or whatever is
+		// queued once FlushInterval passes
+		BatchSize     int           `envconfig:"INGEST_BATCH_SIZE" default:"100"`
+		FlushInterval time.Duration `envconfig:"INGEST_FLUSH_INTERVAL" default:"1s"`
+		// QueueSize is the number of messages waiting for a flush before
+		// handlers start to block
+		QueueSize int `envconfig:"INGEST_QUEUE_SIZE" default:"1000"`
+		// DrainTimeout bounds flushing the queue on shutdown
+		DrainTimeout time.Duration `envconfig:"INGEST_DRAIN_TIMEOUT" default:"10s"`
+	}
+)
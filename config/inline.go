@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type (
+	InlineConfig struct {
+		// Candidates is the number of generated texts offered per query,
+		// taken from up to MaxChats of the chats the user writes to
+		Candidates int `envconfig:"INLINE_CANDIDATES" default:"5"`
+		MaxChats   int `envconfig:"INLINE_MAX_CHATS" default:"5"`
+		// CacheTTL is how long the results of a query are served again
+		// before new ones are generated
+		CacheTTL time.Duration `envconfig:"INLINE_CACHE_TTL" default:"1m"`
+	}
+)
@@ -1,5 +1,18 @@
 package config
 
+import "time"
+
 type MarkovConfig struct {
 	Order int `envconfig:"MARKOV_ORDER" default:"5"`
+	// MaxChains and MaxTokens bound the chains kept in memory, 0 is no limit
+	MaxChains int `envconfig:"MARKOV_MAX_CHAINS" default:"1000"`
+	MaxTokens int `envconfig:"MARKOV_MAX_TOKENS" default:"5000000"`
+	// WarmUpChats is the number of the most active chats loaded on start
+	WarmUpChats int `envconfig:"MARKOV_WARMUP_CHATS" default:"50"`
+	// WarmUpWorkers is the number of chats loaded in parallel
+	WarmUpWorkers int `envconfig:"MARKOV_WARMUP_WORKERS" default:"4"`
+	// WarmUpRetries and WarmUpBackoff control retries of transient
+	// database errors, the backoff doubles after each attempt
+	WarmUpRetries int           `envconfig:"MARKOV_WARMUP_RETRIES" default:"3"`
+	WarmUpBackoff time.Duration `envconfig:"MARKOV_WARMUP_BACKOFF" default:"500ms"`
 }
@@ -0,0 +1,7 @@
+package config
+
+type (
+	MetricsConfig struct {
+		Addr string `envconfig:"METRICS_ADDR" default:":9090"`
+	}
+)
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type (
+	RateLimitConfig struct {
+		UserPerMinute     int            `envconfig:"RATE_LIMIT_USER_PER_MINUTE" default:"20"`
+		ChatPerMinute     int            `envconfig:"RATE_LIMIT_CHAT_PER_MINUTE" default:"60"`
+		Commands          map[string]int `envconfig:"RATE_LIMIT_COMMANDS" default:"gen:6,sticker:10,chart:2"`
+		NoticeInterval    time.Duration  `envconfig:"RATE_LIMIT_NOTICE_INTERVAL" default:"1m"`
+		AutoReplyInterval time.Duration  `envconfig:"AUTO_REPLY_MIN_INTERVAL" default:"30s"`
+	}
+)
@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+type (
+	RetentionConfig struct {
+		// SweepInterval is how often the scheduler enforces the retention
+		// policies of all chats, zero disables the sweep
+		SweepInterval time.Duration `envconfig:"RETENTION_SWEEP_INTERVAL" default:"1h"`
+	}
+)
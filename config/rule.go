@@ -0,0 +1,9 @@
+package config
+
+type (
+	RuleConfig struct {
+		// MaxPerChat bounds the rules a chat can add, they are all matched
+		// against every message. Zero removes the limit.
+		MaxPerChat int `envconfig:"RULES_MAX_PER_CHAT" default:"50"`
+	}
+)
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type (
+	SchedulerConfig struct {
+		Enabled bool `envconfig:"SCHEDULER_ENABLED" default:"true"`
+		// Timezone schedules are written in, e.g. "Europe/Moscow"
+		Timezone   string        `envconfig:"SCHEDULER_TIMEZONE" default:"UTC"`
+		Tick       time.Duration `envconfig:"SCHEDULER_TICK" default:"1m"`
+		JobTimeout time.Duration `envconfig:"SCHEDULER_JOB_TIMEOUT" default:"5m"`
+	}
+)
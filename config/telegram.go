@@ -2,6 +2,9 @@ package config
 
 type (
 	TelegramConfig struct {
-		Token string `envconfig:"BOT_TOKEN" default:""`
+		Token        string  `envconfig:"BOT_TOKEN" default:""`
+		IgnoredChats []int64 `envconfig:"IGNORED_CHATS"`
+		IgnoredUsers []int64 `envconfig:"IGNORED_USERS"`
+		SkipBots     bool    `envconfig:"SKIP_BOT_MESSAGES" default:"true"`
 	}
 )
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type (
+	TranscriberConfig struct {
+		Enabled     bool          `envconfig:"WHISPER_ENABLED" default:"false"`
+		Binary      string        `envconfig:"WHISPER_BINARY" default:"whisper-cli"`
+		Model       string        `envconfig:"WHISPER_MODEL" default:"models/ggml-base.bin"`
+		Language    string        `envconfig:"WHISPER_LANGUAGE" default:"ru"`
+		Threads     int           `envconfig:"WHISPER_THREADS" default:"4"`
+		FFmpeg      string        `envconfig:"FFMPEG_BINARY" default:"ffmpeg"`
+		Workers     int           `envconfig:"TRANSCRIBE_WORKERS" default:"2"`
+		QueueSize   int           `envconfig:"TRANSCRIBE_QUEUE_SIZE" default:"100"`
+		Timeout     time.Duration `envconfig:"TRANSCRIBE_TIMEOUT" default:"2m"`
+		MaxDuration time.Duration `envconfig:"TRANSCRIBE_MAX_DURATION" default:"5m"`
+		// DrainTimeout bounds transcribing the queued voice messages on
+		// shutdown
+		DrainTimeout time.Duration `envconfig:"TRANSCRIBE_DRAIN_TIMEOUT" default:"30s"`
+	}
+)
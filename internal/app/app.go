@@ -1,6 +1,8 @@
 package app
 
 import (
+	"time"
+
 	"github.com/malinatrash/egonez/config"
 	"github.com/malinatrash/egonez/internal/bot"
 	"github.com/malinatrash/egonez/internal/repository"
@@ -8,8 +10,13 @@ import (
 	"go.uber.org/fx"
 )
 
+// stopTimeout bounds stopping the app, which drains the ingestion queue and
+// waits for the scheduled jobs in progress
+const stopTimeout = time.Minute
+
 func New() *fx.App {
 	return fx.New(
+		fx.StopTimeout(stopTimeout),
 		fx.Provide(
 			config.Load,
 			newLogger,
@@ -19,6 +26,8 @@ func New() *fx.App {
 			bot.NewHandler,
 		),
 		fx.Invoke(
+			startMetrics,
+			startScheduler,
 			startBot,
 		),
 	)
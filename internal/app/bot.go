@@ -3,12 +3,43 @@ package app
 import (
 	"context"
 
+	"github.com/malinatrash/egonez/config"
 	"github.com/malinatrash/egonez/internal/bot"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
-func startBot(handler *bot.Handler) error {
+// startBot takes updates while the app is running and stores the queued
+// messages once it stops
+func startBot(lc fx.Lifecycle, handler *bot.Handler, service *usecase.Service, cfg *config.Config, logger *zap.Logger) {
+	// Hooks are stopped in reverse, the queues are drained after the bot has
+	// stopped taking updates. Transcribed voice messages are stored through
+	// the ingestion queue, so it is drained last.
+	lc.Append(fx.Hook{
+		OnStop: func(context.Context) error {
+			if service.TranscriptionService != nil {
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.TranscriberConfig.DrainTimeout)
+				defer cancel()
 
-	bgCtx := context.Background()
+				if err := service.TranscriptionService.Drain(ctx); err != nil {
+					logger.Error("failed to drain transcription queue", zap.Error(err))
+				}
+			}
 
-	return handler.Start(bgCtx)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.IngestConfig.DrainTimeout)
+			defer cancel()
+
+			if err := service.IngestService.Drain(ctx); err != nil {
+				logger.Error("failed to drain ingestion queue", zap.Error(err))
+			}
+			return nil
+		},
+	})
+
+	runInBackground(lc, func(ctx context.Context) {
+		if err := handler.Start(ctx); err != nil {
+			logger.Error("bot stopped", zap.Error(err))
+		}
+	})
 }
@@ -16,10 +16,21 @@ import (
 	"github.com/uptrace/bun/extra/bundebug"
 )
 
+// migrateTimeout bounds creating the tables and running the migrations
+const migrateTimeout = 30 * time.Minute
+
 var tables = []interface{}{
 	(*entity.Message)(nil),
 	(*entity.Sticker)(nil),
+	(*entity.StickerWord)(nil),
+	(*entity.Animation)(nil),
+	(*entity.Schedule)(nil),
 	(*entity.ChatStats)(nil),
+	(*entity.Rule)(nil),
+	(*entity.ChatSettings)(nil),
+	(*entity.Generation)(nil),
+	(*entity.Vote)(nil),
+	(*entity.Quote)(nil),
 }
 
 func NewDatabase(logger *zap.Logger, cfg *config.Config) (*bun.DB, error) {
@@ -51,13 +62,24 @@ func NewDatabase(logger *zap.Logger, cfg *config.Config) (*bun.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Migrations may rewrite or index large tables, they get their own
+	// context rather than the one bounding the ping
+	migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancelMigrate()
+
 	for _, table := range tables {
-		if _, err := db.NewCreateTable().Model(table).Exec(ctx); err != nil {
-			logger.Error("failed to create table", zap.Error(err))
-		} else {
-			logger.Warn("table %s created", zap.String("table", fmt.Sprintf("%T", table)))
+		if _, err := db.NewCreateTable().Model(table).IfNotExists().Exec(migrateCtx); err != nil {
+			return nil, fmt.Errorf("failed to create table %T: %w", table, err)
 		}
 	}
 
+	if err := migrate(migrateCtx, db, logger); err != nil {
+		return nil, err
+	}
+
+	if err := seedDefaultRules(migrateCtx, db); err != nil {
+		return nil, fmt.Errorf("failed to seed default rules: %w", err)
+	}
+
 	return db, nil
 }
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+// runInBackground runs fn from the start of the app until it stops, the
+// context of fn is cancelled on stop and stopping waits for fn to return
+func runInBackground(lc fx.Lifecycle, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				fn(ctx)
+			}()
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return stopCtx.Err()
+			}
+		},
+	})
+}
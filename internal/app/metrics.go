@@ -0,0 +1,27 @@
+package app
+
+import (
+	"errors"
+	"expvar"
+	"net/http"
+
+	"github.com/malinatrash/egonez/config"
+	"go.uber.org/zap"
+)
+
+// startMetrics exposes expvar counters on /debug/vars
+func startMetrics(cfg *config.Config, logger *zap.Logger) {
+	if cfg.MetricsConfig.Addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go func() {
+		err := http.ListenAndServe(cfg.MetricsConfig.Addr, mux)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("metrics server stopped", zap.Error(err))
+		}
+	}()
+}
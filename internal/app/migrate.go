@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+	"go.uber.org/zap"
+)
+
+// migrations bring tables created by older versions up to date, they run on
+// every start and must be idempotent
+var migrations = []string{
+	`ALTER TABLE messages ADD COLUMN IF NOT EXISTS thread_id bigint NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS messages_chat_id_thread_id_idx ON messages (chat_id, thread_id, created_at)`,
+	`ALTER TABLE messages ADD COLUMN IF NOT EXISTS message_id bigint NOT NULL DEFAULT 0`,
+	`ALTER TABLE messages ADD COLUMN IF NOT EXISTS forwarded boolean NOT NULL DEFAULT false`,
+	`ALTER TABLE messages ADD COLUMN IF NOT EXISTS source varchar NOT NULL DEFAULT 'text'`,
+	// Edits upsert the message, which needs it to be unique per source as a
+	// media token and the text of the same message are separate rows.
+	// Messages stored twice before are deduplicated first, keeping the
+	// latest text.
+	`DO $$
+	BEGIN
+		IF to_regclass('messages_chat_id_message_id_key') IS NULL THEN
+			DELETE FROM messages m USING messages d
+			WHERE m.message_id <> 0 AND m.chat_id = d.chat_id AND m.message_id = d.message_id AND m.source = d.source AND m.id < d.id;
+			CREATE UNIQUE INDEX messages_chat_id_message_id_key ON messages (chat_id, message_id, source) WHERE message_id <> 0;
+		END IF;
+	END $$`,
+	`DROP INDEX IF EXISTS messages_chat_id_message_id_idx`,
+	`ALTER TABLE IF EXISTS stickers ADD COLUMN IF NOT EXISTS file_unique_id varchar NOT NULL DEFAULT ''`,
+	`ALTER TABLE IF EXISTS stickers ADD COLUMN IF NOT EXISTS emoji varchar NOT NULL DEFAULT ''`,
+	`ALTER TABLE IF EXISTS stickers ADD COLUMN IF NOT EXISTS type varchar NOT NULL DEFAULT 'static'`,
+	`ALTER TABLE IF EXISTS stickers ADD COLUMN IF NOT EXISTS usage_count bigint NOT NULL DEFAULT 1`,
+	// The old stickers table was unique on file_id across all chats. Move
+	// its rows to chat_stickers, falling back to file_id for stickers stored
+	// before file_unique_id was recorded, and keep it as stickers_legacy.
+	// Rows keyed on file_id are merged by the repository once the sticker is
+	// seen again with its file_unique_id.
+	`DO $$
+	BEGIN
+		IF to_regclass('stickers') IS NOT NULL THEN
+			INSERT INTO chat_stickers (chat_id, file_unique_id, file_id, set_name, emoji, type, usage_count, created_at, last_seen_at)
+			SELECT chat_id, COALESCE(NULLIF(file_unique_id, ''), file_id), MAX(file_id), MAX(set_name), MAX(emoji), MAX(type),
+				SUM(usage_count), MIN(created_at), MAX(created_at)
+			FROM stickers
+			GROUP BY chat_id, COALESCE(NULLIF(file_unique_id, ''), file_id)
+			ON CONFLICT (chat_id, file_unique_id) DO NOTHING;
+			ALTER TABLE stickers RENAME TO stickers_legacy;
+		END IF;
+	END $$`,
+	`CREATE INDEX IF NOT EXISTS chat_stickers_chat_id_emoji_idx ON chat_stickers (chat_id, emoji)`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS topic_chains boolean NOT NULL DEFAULT false`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS learn_forwards boolean NOT NULL DEFAULT false`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS retention_days integer NOT NULL DEFAULT 0`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS retention_messages integer NOT NULL DEFAULT 0`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS language varchar NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS messages_user_id_idx ON messages (user_id, created_at)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS generations_chat_id_message_id_idx ON generations (chat_id, message_id)`,
+	`ALTER TABLE generation_votes ADD COLUMN IF NOT EXISTS source varchar NOT NULL DEFAULT 'button'`,
+	`CREATE INDEX IF NOT EXISTS generations_chat_id_likes_idx ON generations (chat_id, likes DESC)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS quotes_chat_id_message_id_idx ON quotes (chat_id, message_id)`,
+	// Retention jobs used to take the days to keep, which now is the
+	// retention policy of the chat they apply
+	`INSERT INTO chat_settings (chat_id, retention_days)
+	SELECT chat_id, arg::integer FROM schedules WHERE job = 'retention' AND arg ~ '^[0-9]+$'
+	ON CONFLICT (chat_id) DO UPDATE SET retention_days = EXCLUDED.retention_days
+	WHERE chat_settings.retention_days = 0`,
+	`UPDATE schedules SET arg = '' WHERE job = 'retention' AND arg <> ''`,
+}
+
+// migrate runs the migrations in order and stops at the first failing one
+func migrate(ctx context.Context, db *bun.DB, logger *zap.Logger) error {
+	for i, query := range migrations {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			logger.Error("failed to run migration", zap.String("query", query), zap.Error(err))
+			return fmt.Errorf("failed to run migration %d: %w", i, err)
+		}
+	}
+	return nil
+}
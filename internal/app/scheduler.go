@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+
+	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/bot"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/repository"
+	"github.com/malinatrash/egonez/internal/scheduler"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// startScheduler runs the scheduled jobs of all chats, and the sweep
+// enforcing their retention policies, in the background while the app is
+// running
+func startScheduler(
+	lc fx.Lifecycle,
+	cfg *config.Config,
+	repo *repository.Repository,
+	service *usecase.Service,
+	handler *bot.Handler,
+	logger *zap.Logger,
+) error {
+	sweep := cfg.RetentionConfig.SweepInterval
+	if !cfg.SchedulerConfig.Enabled && sweep <= 0 {
+		return nil
+	}
+
+	s, err := scheduler.New(repo.ScheduleRepository, cfg.SchedulerConfig, logger)
+	if err != nil {
+		return err
+	}
+
+	if cfg.SchedulerConfig.Enabled {
+		s.Register(entity.JobQuote, handler.PostQuote)
+		s.Register(entity.JobDigest, handler.PostDigest)
+		s.Register(entity.JobRetrain, func(ctx context.Context, schedule *entity.Schedule) error {
+			return service.BotService.Retrain(ctx, schedule.ChatID)
+		})
+		s.Register(entity.JobRetention, func(ctx context.Context, schedule *entity.Schedule) error {
+			_, err := service.RetentionService.Apply(ctx, schedule.ChatID)
+			return err
+		})
+	}
+
+	if sweep > 0 {
+		s.Every("retention_sweep", sweep, func(ctx context.Context) error {
+			deleted, err := service.RetentionService.Sweep(ctx)
+			if err != nil {
+				return err
+			}
+			logger.Info("retention sweep done", zap.Int64("deleted", deleted))
+			return nil
+		})
+	}
+
+	runInBackground(lc, s.Run)
+	return nil
+}
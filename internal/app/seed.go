@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/uptrace/bun"
+)
+
+// defaultRules are the global replies the bot used to have hard-coded
+var defaultRules = []*entity.Rule{
+	{MatchType: entity.RuleMatchWord, Pattern: "соси", ResponseType: entity.RuleResponseText, Response: "сам соси", Probability: 1},
+	{MatchType: entity.RuleMatchRegex, Pattern: `(?i)сосал\?`, ResponseType: entity.RuleResponseText, Response: "сосал", Probability: 1},
+	{MatchType: entity.RuleMatchBoost, ResponseType: entity.RuleResponseText, Response: "нихуя себе!", Probability: 1},
+	{MatchType: entity.RuleMatchVoice, ResponseType: entity.RuleResponseText, Response: "суки я не умею слушать", Probability: 0.15},
+	{MatchType: entity.RuleMatchVideo, ResponseType: entity.RuleResponseText, Response: "суки я не умею слушать", Probability: 0.15},
+}
+
+// seedDefaultRules stores the default global rules unless there are some already
+func seedDefaultRules(ctx context.Context, db *bun.DB) error {
+	exists, err := db.NewSelect().
+		Model((*entity.Rule)(nil)).
+		Where("chat_id = ?", entity.GlobalChatID).
+		Exists(ctx)
+	if err != nil || exists {
+		return err
+	}
+
+	for _, rule := range defaultRules {
+		rule.ChatID = entity.GlobalChatID
+	}
+
+	_, err = db.NewInsert().Model(&defaultRules).Exec(ctx)
+	return err
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// isAdmin reports whether the user administers the chat, everyone is an
+// admin of their private chat with the bot
+func (h *Handler) isAdmin(ctx context.Context, chat models.Chat, userID int64) bool {
+	if chat.Type == models.ChatTypePrivate {
+		return true
+	}
+
+	member, err := h.bot.GetChatMember(ctx, &bot.GetChatMemberParams{
+		ChatID: chat.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get chat member",
+			zap.Int64("chat_id", chat.ID),
+			zap.Int64("user_id", userID),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator
+}
@@ -0,0 +1,14 @@
+package bot
+
+import (
+	"strings"
+)
+
+// commandArgs returns the text following the command, e.g. "10" for "/gen@bot 10"
+func commandArgs(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \n\t"); i >= 0 {
+		return strings.TrimSpace(text[i+1:])
+	}
+	return ""
+}
@@ -3,10 +3,11 @@ package bot
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/signal"
+	"regexp"
+	"time"
 
 	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/bot/middleware"
 	"github.com/malinatrash/egonez/internal/usecase"
 	"go.uber.org/zap"
 
@@ -18,41 +19,103 @@ type Handler struct {
 	service *usecase.Service
 	logger  *zap.Logger
 	bot     *bot.Bot
+	limiter *rateLimiter
+	me      *models.User
+	mention *regexp.Regexp
+
+	maxVoiceDuration time.Duration
+	// location is the time zone schedules are shown in
+	location *time.Location
+	// registry lists the commands of the bot
+	registry []command
+	inline   *inlineCache
 }
 
 func NewHandler(config *config.Config, service *usecase.Service, logger *zap.Logger) (*Handler, error) {
 
 	h := &Handler{}
 
-	opts := []bot.Option{
-		bot.WithDefaultHandler(h.defaultHandler),
+	b, err := bot.New(config.TelegramConfig.Token, h.options(config.TelegramConfig, logger)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
-	b, err := bot.New(config.TelegramConfig.Token, opts...)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	me, err := b.GetMe(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create bot: %w", err)
+		return nil, fmt.Errorf("failed to get bot info: %w", err)
 	}
 
+	h.me = me
+	h.mention = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(me.Username) + `\b`)
 	h.service = service
 	h.logger = logger
 	h.bot = b
+	h.limiter = newRateLimiter(config.RateLimitConfig)
+	h.maxVoiceDuration = config.TranscriberConfig.MaxDuration
+	h.location = time.UTC
+	if loc, err := time.LoadLocation(config.SchedulerConfig.Timezone); err == nil {
+		h.location = loc
+	}
+
+	h.inline = newInlineCache(config.InlineConfig)
 
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypeExact, h.handleStart)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/help", bot.MatchTypeExact, h.handleHelp)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/gen", bot.MatchTypeExact, h.handleGenerate)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/clear", bot.MatchTypeExact, h.handleClear)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/sticker", bot.MatchTypeExact, h.handleSticker)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/stats", bot.MatchTypeExact, h.handleStats)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "", bot.MatchTypeContains, h.handleTextMessage, h.Middleware)
+	h.register(b)
+
+	commandsCtx, cancelCommands := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelCommands()
+	h.setCommands(commandsCtx)
 
 	return h, nil
 }
 
-func (h *Handler) Start(ctx context.Context) error {
+// options configures the bot to pass every update through the middlewares,
+// updates that no handler matches go to the default handler
+func (h *Handler) options(cfg config.TelegramConfig, logger *zap.Logger) []bot.Option {
+	middlewares := []bot.Middleware{
+		middleware.Recover(logger),
+		middleware.Logging(logger),
+		middleware.Metrics(),
+		middleware.RequireMessage(),
+		middleware.Ignore(cfg.IgnoredChats, cfg.IgnoredUsers),
+	}
+	if cfg.SkipBots {
+		middlewares = append(middlewares, middleware.SkipBots())
+	}
 
-	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
-	defer cancel()
+	return []bot.Option{
+		bot.WithDefaultHandler(h.defaultHandler),
+		bot.WithMiddlewares(middlewares...),
+		bot.WithSkipGetMe(),
+		// Reactions are only sent when asked for, and only to bots
+		// administering the chat
+		bot.WithAllowedUpdates(bot.AllowedUpdates{
+			models.AllowedUpdateMessage,
+			models.AllowedUpdateEditedMessage,
+			models.AllowedUpdateChannelPost,
+			models.AllowedUpdateEditedChannelPost,
+			models.AllowedUpdateInlineQuery,
+			models.AllowedUpdateCallbackQuery,
+			models.AllowedUpdateMessageReaction,
+		}),
+	}
+}
+
+// register routes commands, inline queries, feedback buttons and reactions
+// to their handlers. Every other message, whether it is text or media, is
+// left to the default handler.
+func (h *Handler) register(b *bot.Bot) {
+	h.registry = h.commands()
+	h.registerCommands(b)
+	b.RegisterHandlerMatchFunc(func(update *models.Update) bool { return update.InlineQuery != nil }, h.handleInlineQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, feedbackPrefix, bot.MatchTypePrefix, h.handleFeedback)
+	b.RegisterHandlerMatchFunc(func(update *models.Update) bool { return update.MessageReaction != nil }, h.handleReaction)
+}
 
+// Start takes updates until the context is done
+func (h *Handler) Start(ctx context.Context) error {
 	h.bot.Start(ctx)
 	return nil
 }
@@ -69,8 +132,8 @@ func (h *Handler) handleClear(ctx context.Context, b *bot.Bot, update *models.Up
 
 	chatID := update.Message.Chat.ID
 	if err := h.service.BotService.ClearChatHistory(ctx, chatID); err != nil {
-		h.sendMessage(ctx, chatID, "❌ Не удалось очистить историю чата. Попробуйте позже.")
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "clear.failed"))
 		return
 	}
-	h.sendMessage(ctx, chatID, "🧹 История чата была очищена!")
+	h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "clear.done"))
 }
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+
+	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+// fakeBotService records the stickers and messages it is given
+type fakeBotService struct {
+	adapters.Bot
+
+	mu       sync.Mutex
+	stickers []*entity.Sticker
+	messages []*entity.Message
+}
+
+func (s *fakeBotService) HandleSticker(ctx context.Context, sticker *entity.Sticker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stickers = append(s.stickers, sticker)
+	return nil
+}
+
+func (s *fakeBotService) HandleMessage(ctx context.Context, message *entity.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// fakeRules never matches
+type fakeRules struct {
+	adapters.Rules
+}
+
+func (fakeRules) Match(ctx context.Context, chatID int64, text string, kinds ...entity.RuleMatchType) (*entity.RuleMatch, error) {
+	return nil, nil
+}
+
+// fakeTranscription records the queued jobs
+type fakeTranscription struct {
+	adapters.Transcription
+
+	mu   sync.Mutex
+	jobs []adapters.VoiceJob
+}
+
+func (t *fakeTranscription) Enqueue(job adapters.VoiceJob) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.jobs = append(t.jobs, job)
+	return true
+}
+
+// newTestHandler wires the handler to a bot talking to a fake Bot API, the
+// updates are handled synchronously
+func newTestHandler(t *testing.T, service *usecase.Service) (*Handler, *bot.Bot) {
+	t.Helper()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	t.Cleanup(api.Close)
+
+	h := &Handler{
+		service:          service,
+		logger:           zap.NewNop(),
+		me:               &models.User{ID: 1, Username: "egonez_bot", IsBot: true},
+		limiter:          newRateLimiter(config.RateLimitConfig{UserPerMinute: 60, ChatPerMinute: 60}),
+		maxVoiceDuration: time.Minute,
+		location:         time.UTC,
+	}
+
+	opts := append(h.options(config.TelegramConfig{}, h.logger), bot.WithServerURL(api.URL), bot.WithNotAsyncHandlers())
+	b, err := bot.New("1:test", opts...)
+	if err != nil {
+		t.Fatalf("bot.New: %v", err)
+	}
+
+	h.bot = b
+	h.register(b)
+	return h, b
+}
+
+func testMessage() *models.Message {
+	return &models.Message{
+		ID:   42,
+		Date: int(time.Now().Unix()),
+		Chat: models.Chat{ID: -100, Type: models.ChatTypeSupergroup},
+		From: &models.User{ID: 7, FirstName: "Alice"},
+	}
+}
+
+func TestStickerUpdate(t *testing.T) {
+	botService := &fakeBotService{}
+	_, b := newTestHandler(t, &usecase.Service{BotService: botService, RuleService: fakeRules{}})
+
+	msg := testMessage()
+	msg.Sticker = &models.Sticker{FileID: "file", FileUniqueID: "unique", Emoji: "🙂"}
+	b.ProcessUpdate(context.Background(), &models.Update{Message: msg})
+
+	if len(botService.stickers) != 1 || botService.stickers[0].FileUniqueID != "unique" {
+		t.Fatalf("stored stickers = %+v, want the sticker", botService.stickers)
+	}
+	if len(botService.messages) != 1 || botService.messages[0].Source != entity.MessageSourceSticker {
+		t.Fatalf("learnt messages = %+v, want the sticker token", botService.messages)
+	}
+}
+
+func TestVoiceUpdate(t *testing.T) {
+	transcription := &fakeTranscription{}
+	_, b := newTestHandler(t, &usecase.Service{
+		BotService:           &fakeBotService{},
+		RuleService:          fakeRules{},
+		TranscriptionService: transcription,
+	})
+
+	msg := testMessage()
+	msg.Voice = &models.Voice{FileID: "voice", Duration: 5}
+	b.ProcessUpdate(context.Background(), &models.Update{Message: msg})
+
+	if len(transcription.jobs) != 1 || transcription.jobs[0].FileID != "voice" {
+		t.Fatalf("queued jobs = %+v, want the voice message", transcription.jobs)
+	}
+	if job := transcription.jobs[0]; job.Message.ChatID != msg.Chat.ID || job.Message.MessageID != int64(msg.ID) {
+		t.Errorf("job message = %+v, want chat %d message %d", job.Message, msg.Chat.ID, msg.ID)
+	}
+}
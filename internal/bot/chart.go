@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) handleChart(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleChart"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil {
+		logger.Error("update.Message is nil")
+		return
+	}
+
+	period, ok := parseStatsPeriod(commandArgs(update.Message.Text))
+	if !ok {
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "chart.usage"))
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	stats, err := h.service.BotService.GetChatStats(ctx, chatID, period)
+	if err != nil {
+		logger.Error("Failed to get chat stats", zap.Error(err))
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "stats.failed"))
+		return
+	}
+
+	locale := h.locale(ctx, update.Message)
+	h.resolveNames(ctx, chatID, locale, stats.TopUsers)
+
+	image, err := h.service.ChartService.Render(stats, entity.ChartLabels{
+		Daily:    i18n.T(locale, "chart.daily"),
+		Users:    i18n.T(locale, "chart.users"),
+		Messages: i18n.T(locale, "chart.messages"),
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrNoChartData) {
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "chart.no_data"))
+			return
+		}
+		logger.Error("Failed to render chart", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "chart.failed"))
+		return
+	}
+
+	_, err = h.bot.SendPhoto(ctx, &bot.SendPhotoParams{
+		ChatID:          chatID,
+		MessageThreadID: topicID(update.Message),
+		Photo: &models.InputFileUpload{
+			Filename: "chart.png",
+			Data:     bytes.NewReader(image),
+		},
+		Caption: i18n.T(locale, "chart.caption", periodTitle(locale, period)),
+	})
+	if err != nil {
+		logger.Error("Failed to send chart", zap.Error(err))
+		return
+	}
+	h.limiter.markSent(chatID)
+}
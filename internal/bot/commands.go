@@ -0,0 +1,239 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"go.uber.org/zap"
+)
+
+// scope limits the chats a command is offered in
+type scope int
+
+const (
+	scopeAll scope = iota
+	scopePrivate
+	scopeGroup
+)
+
+// command is a bot command together with everything needed to register it,
+// describe it in /help and list it in the Telegram command menu
+type command struct {
+	name    string
+	handler bot.HandlerFunc
+	// args is the catalog key of the arguments shown after the command,
+	// empty for commands without arguments
+	args string
+	// maxArgs bounds the number of space separated arguments, the usage
+	// is shown above it. A negative value means any number.
+	maxArgs int
+	// admin commands may only be run by chat administrators
+	admin bool
+	scope scope
+	// featured commands are introduced in the /start greeting
+	featured bool
+}
+
+// commands returns the commands of the bot in the order they are shown. The
+// description of a command is the catalog key "command.<name>".
+func (h *Handler) commands() []command {
+	return []command{
+		// start takes the deep link payload, e.g. from the inline mode button
+		{name: "start", handler: h.handleStart, maxArgs: 1, scope: scopePrivate},
+		{name: "help", handler: h.handleHelp},
+		{name: "gen", handler: h.handleGenerate, featured: true},
+		{name: "clear", handler: h.handleClear, featured: true},
+		{name: "sticker", handler: h.handleSticker, args: "args.sticker", maxArgs: 1, featured: true},
+		{name: "stats", handler: h.handleStats, args: "args.period", maxArgs: 1, featured: true},
+		{name: "chart", handler: h.handleChart, args: "args.period", maxArgs: 1},
+		{name: "top", handler: h.handleTop},
+		{name: "quote", handler: h.handleQuote, args: "args.quote", maxArgs: -1, featured: true},
+		{name: "wake", handler: h.handleWake, args: "args.wake", maxArgs: -1, scope: scopeGroup, admin: true},
+		{name: "topics", handler: h.handleTopics, args: "args.toggle", maxArgs: 1, scope: scopeGroup, admin: true},
+		{name: "forwards", handler: h.handleForwards, args: "args.toggle", maxArgs: 1, admin: true},
+		{name: "language", handler: h.handleLanguage, args: "args.language", maxArgs: 1, admin: true},
+		{name: "schedule", handler: h.handleSchedule, args: "args.schedule", maxArgs: -1, admin: true},
+		{name: "retention", handler: h.handleRetention, args: "args.retention", maxArgs: 2, admin: true},
+		{name: "rules", handler: h.handleRules},
+		{name: "rule_add", handler: h.handleRuleAdd, args: "args.rule_add", maxArgs: -1, admin: true},
+		{name: "rule_del", handler: h.handleRuleDelete, args: "args.rule_del", maxArgs: 1, admin: true},
+	}
+}
+
+// registerCommands registers a handler for every command, rate limited
+// under the command's name
+func (h *Handler) registerCommands(b *bot.Bot) {
+	for _, cmd := range h.registry {
+		b.RegisterHandlerMatchFunc(h.matchCommand(cmd.name), h.dispatch(cmd), h.RateLimit(cmd.name))
+	}
+}
+
+// parseCommand splits "/name@bot args" into the name and the arguments, ok
+// is false for text that isn't a command or is a command for another bot
+func (h *Handler) parseCommand(text string) (name, args string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	head := text[1:]
+	if i := strings.IndexAny(head, " \n\t"); i >= 0 {
+		head, args = head[:i], strings.TrimSpace(head[i+1:])
+	}
+
+	name, username, addressed := strings.Cut(head, "@")
+	if addressed && !strings.EqualFold(username, h.me.Username) {
+		return "", "", false
+	}
+
+	return strings.ToLower(name), args, name != ""
+}
+
+func (h *Handler) matchCommand(name string) bot.MatchFunc {
+	return func(update *models.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		parsed, _, ok := h.parseCommand(update.Message.Text)
+		return ok && parsed == name
+	}
+}
+
+// dispatch checks the permission and the number of arguments before
+// running the command
+func (h *Handler) dispatch(cmd command) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		const op = "bot/handler.dispatch"
+
+		msg := update.Message
+		if msg == nil || msg.From == nil {
+			h.logger.Error("update.Message or update.Message.From is nil", zap.String("op", op))
+			return
+		}
+
+		if cmd.admin && !h.isAdmin(ctx, msg.Chat, msg.From.ID) {
+			h.sendMessage(ctx, msg, h.t(ctx, msg, "command.admin_only"))
+			return
+		}
+
+		_, args, _ := h.parseCommand(msg.Text)
+		if cmd.maxArgs >= 0 && len(strings.Fields(args)) > cmd.maxArgs {
+			locale := h.locale(ctx, msg)
+			h.sendMessage(ctx, msg, i18n.T(locale, "command.usage", cmd.usage(locale)))
+			return
+		}
+
+		cmd.handler(ctx, b, update)
+	}
+}
+
+// usage returns the command with its arguments, e.g. "/stats [week|month|all]"
+func (c command) usage(locale string) string {
+	if c.args == "" {
+		return "/" + c.name
+	}
+	return "/" + c.name + " " + i18n.T(locale, c.args)
+}
+
+// offered reports whether the command is offered in chats of the type
+func (c command) offered(chatType models.ChatType) bool {
+	switch c.scope {
+	case scopePrivate:
+		return chatType == models.ChatTypePrivate
+	case scopeGroup:
+		return chatType != models.ChatTypePrivate
+	default:
+		return true
+	}
+}
+
+// markdownEscaper escapes the characters legacy Markdown would take for
+// formatting
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+
+// helpText lists the commands offered in chats of the type
+func (h *Handler) helpText(locale string, chatType models.ChatType) string {
+	var sb strings.Builder
+
+	sb.WriteString(i18n.T(locale, "help.header") + "\n")
+	for _, cmd := range h.registry {
+		if !cmd.offered(chatType) {
+			continue
+		}
+
+		sb.WriteString(markdownEscaper.Replace(cmd.usage(locale)))
+		sb.WriteString(" - " + markdownEscaper.Replace(i18n.T(locale, "command."+cmd.name)))
+		if cmd.admin && chatType != models.ChatTypePrivate {
+			sb.WriteString(" " + i18n.T(locale, "help.admin"))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n" + i18n.T(locale, "help.footer"))
+
+	return sb.String()
+}
+
+// startText greets the user introducing the featured commands
+func (h *Handler) startText(locale string) string {
+	var sb strings.Builder
+
+	sb.WriteString(i18n.T(locale, "start.intro") + "\n")
+	for _, cmd := range h.registry {
+		if cmd.featured {
+			sb.WriteString("- /" + cmd.name + " — " + i18n.T(locale, "command."+cmd.name) + "\n")
+		}
+	}
+	sb.WriteString("\n" + i18n.T(locale, "start.more"))
+
+	return sb.String()
+}
+
+// setCommands fills the Telegram command menu: private chats get the
+// commands offered there, group members the ones they may run and group
+// administrators all group commands, in every locale of the catalog
+func (h *Handler) setCommands(ctx context.Context) {
+	scopes := []struct {
+		scope    models.BotCommandScope
+		chatType models.ChatType
+		admin    bool
+	}{
+		{&models.BotCommandScopeAllPrivateChats{}, models.ChatTypePrivate, true},
+		{&models.BotCommandScopeAllGroupChats{}, models.ChatTypeGroup, false},
+		{&models.BotCommandScopeAllChatAdministrators{}, models.ChatTypeGroup, true},
+	}
+
+	for _, locale := range i18n.Locales() {
+		// The default locale serves clients of every other language too
+		languageCode := locale
+		if locale == i18n.DefaultLocale {
+			languageCode = ""
+		}
+
+		for _, s := range scopes {
+			var commands []models.BotCommand
+			for _, cmd := range h.registry {
+				if !cmd.offered(s.chatType) || (cmd.admin && !s.admin) {
+					continue
+				}
+				commands = append(commands, models.BotCommand{
+					Command:     cmd.name,
+					Description: i18n.T(locale, "command."+cmd.name),
+				})
+			}
+
+			_, err := h.bot.SetMyCommands(ctx, &bot.SetMyCommandsParams{
+				Commands:     commands,
+				Scope:        s.scope,
+				LanguageCode: languageCode,
+			})
+			if err != nil {
+				h.logger.Error("failed to set bot commands",
+					zap.String("locale", locale),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
@@ -2,49 +2,46 @@ package bot
 
 import (
 	"context"
-	"math/rand"
 	"strings"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
+// defaultHandler takes the updates no command or other handler matched:
+// edits, channel posts and every new message, media stored before the text
+// or caption is learnt and replied to
 func (h *Handler) defaultHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	h.logger.Warn("default handler")
-
-	if update.Message.BoostAdded != nil {
-		h.logger.Info("Boost added")
-
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "нихуя себе!",
-		})
+	switch {
+	case update.EditedMessage != nil:
+		h.handleEditedMessage(ctx, update.EditedMessage)
+		return
+	case update.EditedChannelPost != nil:
+		h.handleEditedMessage(ctx, update.EditedChannelPost)
+		return
+	case update.ChannelPost != nil:
+		h.learn(ctx, update.ChannelPost, messageText(update.ChannelPost))
+		return
+	case update.Message == nil:
+		return
 	}
 
 	if update.Message.Sticker != nil {
-		h.logger.Info("Sticker message received")
-
 		h.handleStickerMessage(ctx, b, update)
 	}
 
-	if strings.Contains(update.Message.Text, "соси") {
-		h.logger.Info("Command message received")
-
-		h.sendMessage(ctx, update.Message.Chat.ID, "сам соси")
+	if update.Message.Animation != nil {
+		h.handleAnimationMessage(ctx, update.Message)
 	}
 
-	if strings.Contains(update.Message.Text, "сосал?") {
-		h.logger.Info("Command message received")
-
-		h.sendMessage(ctx, update.Message.Chat.ID, "сосал")
+	if update.Message.Voice != nil || update.Message.VideoNote != nil {
+		h.handleVoiceMessage(ctx, update.Message)
 	}
 
-	if update.Message.Video != nil || update.Message.VideoNote != nil || update.Message.Voice != nil {
-		h.logger.Info("Video message received")
-
-		chance := rand.Intn(100)
-		if chance < 15 {
-			h.sendMessage(ctx, update.Message.Chat.ID, "суки я не умею слушать")
-		}
+	// Commands for other bots or unknown ones land here too, rules don't
+	// answer them
+	if !strings.HasPrefix(messageText(update.Message), "/") {
+		h.applyRules(ctx, update.Message)
 	}
+	h.handleTextMessage(ctx, b, update)
 }
@@ -0,0 +1,248 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// Callback data of the buttons under generated messages
+const (
+	feedbackPrefix     = "fb:"
+	feedbackRegenerate = feedbackPrefix + "regen"
+	feedbackLike       = feedbackPrefix + "like"
+	feedbackDislike    = feedbackPrefix + "dislike"
+	feedbackDelete     = feedbackPrefix + "delete"
+)
+
+// feedbackKeyboard returns the buttons shown under a generated message
+func feedbackKeyboard(generation *entity.Generation) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{{
+			{Text: "🔁", CallbackData: feedbackRegenerate},
+			{Text: counted("👍", generation.Likes), CallbackData: feedbackLike},
+			{Text: counted("👎", generation.Dislikes), CallbackData: feedbackDislike},
+			{Text: "🗑", CallbackData: feedbackDelete},
+		}},
+	}
+}
+
+func counted(label string, n int) string {
+	if n == 0 {
+		return label
+	}
+	return fmt.Sprintf("%s %d", label, n)
+}
+
+// sendRated sends a generated text with the feedback buttons and records
+// it, so that the votes can be traced back to the text
+func (h *Handler) sendRated(ctx context.Context, to *models.Message, text, seed string, reply bool) {
+	const op = "bot/handler.sendRated"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	generation := &entity.Generation{
+		ChatID:      to.Chat.ID,
+		ThreadID:    int64(topicID(to)),
+		RequestedBy: senderID(to),
+		Seed:        seed,
+		Text:        text,
+	}
+
+	params := &bot.SendMessageParams{
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Text:            text,
+		ReplyMarkup:     feedbackKeyboard(generation),
+	}
+	if reply {
+		params.ReplyParameters = replyTo(to)
+	}
+
+	sent, err := h.bot.SendMessage(ctx, params)
+	h.limiter.markSent(to.Chat.ID)
+	if err != nil {
+		logger.Error("failed to send generated message", zap.Error(err))
+		return
+	}
+
+	generation.MessageID = int64(sent.ID)
+	if err := h.service.FeedbackService.Record(ctx, generation); err != nil {
+		logger.Error("failed to record generation", zap.Error(err))
+	}
+}
+
+// handleFeedback handles the buttons under generated messages
+func (h *Handler) handleFeedback(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleFeedback"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	query := update.CallbackQuery
+	if query == nil {
+		logger.Error("update.CallbackQuery is nil")
+		return
+	}
+
+	notice := ""
+	defer func() {
+		_, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: query.ID,
+			Text:            notice,
+		})
+		if err != nil {
+			logger.Error("failed to answer callback query", zap.Error(err))
+		}
+	}()
+
+	// Messages older than 48 hours are inaccessible
+	msg := query.Message.Message
+	if msg == nil {
+		return
+	}
+
+	locale := h.locale(ctx, msg)
+	userID := query.From.ID
+
+	if !h.limiter.allow("feedback", msg.Chat.ID, userID) {
+		notice = i18n.T(locale, "ratelimit.notice")
+		return
+	}
+
+	generation, err := h.service.FeedbackService.Get(ctx, msg.Chat.ID, int64(msg.ID))
+	if err != nil {
+		logger.Error("failed to get generation", zap.Error(err))
+		notice = i18n.T(locale, "feedback.failed")
+		return
+	}
+	if generation == nil {
+		notice = i18n.T(locale, "feedback.unknown")
+		return
+	}
+
+	switch query.Data {
+	case feedbackLike:
+		notice = h.rate(ctx, msg, locale, generation, userID, 1)
+	case feedbackDislike:
+		notice = h.rate(ctx, msg, locale, generation, userID, -1)
+	case feedbackRegenerate:
+		if !h.mayManage(ctx, msg.Chat, generation, userID) {
+			notice = i18n.T(locale, "feedback.not_allowed")
+			return
+		}
+		notice = h.regenerate(ctx, msg, locale, generation)
+	case feedbackDelete:
+		if !h.mayManage(ctx, msg.Chat, generation, userID) {
+			notice = i18n.T(locale, "feedback.not_allowed")
+			return
+		}
+		notice = h.deleteGenerated(ctx, msg, locale, generation)
+	}
+}
+
+// mayManage reports whether the user may regenerate or delete the message,
+// which is the user it answers and the chat administrators
+func (h *Handler) mayManage(ctx context.Context, chat models.Chat, generation *entity.Generation, userID int64) bool {
+	return generation.RequestedBy == userID || h.isAdmin(ctx, chat, userID)
+}
+
+// rate records the vote and returns the notice shown to the user
+func (h *Handler) rate(ctx context.Context, msg *models.Message, locale string, generation *entity.Generation, userID int64, value int) string {
+	const op = "bot/handler.rate"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	vote, err := h.service.FeedbackService.Rate(ctx, generation, userID, value)
+	if err != nil {
+		logger.Error("failed to rate generation", zap.Error(err))
+		return i18n.T(locale, "feedback.failed")
+	}
+
+	_, err = h.bot.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		ReplyMarkup: feedbackKeyboard(generation),
+	})
+	if err != nil {
+		logger.Error("failed to update feedback buttons", zap.Error(err))
+	}
+
+	switch {
+	case vote > 0:
+		return i18n.T(locale, "feedback.liked")
+	case vote < 0:
+		return i18n.T(locale, "feedback.disliked")
+	default:
+		return i18n.T(locale, "feedback.withdrawn")
+	}
+}
+
+// regenerate replaces the text of the message with a new one generated from
+// the same seed and returns the notice shown to the user
+func (h *Handler) regenerate(ctx context.Context, msg *models.Message, locale string, generation *entity.Generation) string {
+	const op = "bot/handler.regenerate"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	text, err := h.service.BotService.GenerateResponse(ctx, generation.ChatID, generation.ThreadID, generation.Seed)
+	switch {
+	case errors.Is(err, usecase.ErrNotEnoughData):
+		return i18n.T(locale, "generate.no_data")
+	case errors.Is(err, usecase.ErrWarmingUp):
+		return i18n.T(locale, "generate.warming_up")
+	case err != nil:
+		logger.Error("failed to generate response", zap.Error(err))
+		return i18n.T(locale, "generate.failed")
+	}
+
+	// The message stays a text one, media drawn this time is left out
+	text = stripMedia(text)
+	if text == "" || text == generation.Text {
+		return i18n.T(locale, "feedback.same")
+	}
+
+	_, err = h.bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		Text:        text,
+		ReplyMarkup: feedbackKeyboard(&entity.Generation{}),
+	})
+	if err != nil {
+		logger.Error("failed to edit generated message", zap.Error(err))
+		return i18n.T(locale, "feedback.failed")
+	}
+
+	if err := h.service.FeedbackService.Replace(ctx, generation, text); err != nil {
+		logger.Error("failed to replace generation", zap.Error(err))
+	}
+	return ""
+}
+
+// deleteGenerated deletes the message and returns the notice shown to the
+// user
+func (h *Handler) deleteGenerated(ctx context.Context, msg *models.Message, locale string, generation *entity.Generation) string {
+	const op = "bot/handler.deleteGenerated"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	_, err := h.bot.DeleteMessage(ctx, &bot.DeleteMessageParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+	})
+	if err != nil {
+		logger.Error("failed to delete generated message", zap.Error(err))
+		return i18n.T(locale, "feedback.failed")
+	}
+
+	if err := h.service.FeedbackService.Delete(ctx, generation); err != nil {
+		logger.Error("failed to delete generation", zap.Error(err))
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+)
+
+var forwardsToggle = toggle{
+	command: "forwards",
+	get:     func(settings *entity.ChatSettings) bool { return settings.LearnForwards },
+	set:     func(settings *entity.ChatSettings, enabled bool) { settings.LearnForwards = enabled },
+	state:   "forwards.state",
+	on:      "forwards.on",
+	off:     "forwards.off",
+}
+
+func (h *Handler) handleForwards(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.handleToggle(ctx, update, forwardsToggle)
+}
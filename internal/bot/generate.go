@@ -2,9 +2,11 @@ package bot
 
 import (
 	"context"
+	"errors"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/usecase"
 	"go.uber.org/zap"
 )
 
@@ -18,11 +20,32 @@ func (h *Handler) handleGenerate(ctx context.Context, b *bot.Bot, update *models
 		return
 	}
 
-	chatID := update.Message.Chat.ID
-	msg, err := h.service.BotService.GenerateResponse(ctx, chatID)
-	if err != nil {
-		h.sendMessage(ctx, chatID, "❌ Failed to generate response. Please try again later.")
-		return
+	if response, ok := h.generate(ctx, update.Message, "", true); ok {
+		h.sendGenerated(ctx, update.Message, response, "", false)
+	}
+}
+
+// generate returns a response to the message generated from the history of
+// its chat or topic, seeded by seed. The user is notified if it fails and
+// notify is set, auto replies fail silently.
+func (h *Handler) generate(ctx context.Context, to *models.Message, seed string, notify bool) (string, bool) {
+	msg, err := h.service.BotService.GenerateResponse(ctx, to.Chat.ID, int64(topicID(to)), seed)
+	if err == nil {
+		return msg, true
+	}
+
+	key := "generate.failed"
+	switch {
+	case errors.Is(err, usecase.ErrNotEnoughData):
+		key = "generate.no_data"
+	case errors.Is(err, usecase.ErrWarmingUp):
+		key = "generate.warming_up"
+	default:
+		h.logger.Error("Failed to generate response", zap.String("op", "bot/handler.generate"), zap.Error(err))
+	}
+
+	if notify {
+		h.sendMessage(ctx, to, h.t(ctx, to, key))
 	}
-	h.sendMessage(ctx, chatID, msg)
+	return "", false
 }
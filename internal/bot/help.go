@@ -18,19 +18,10 @@ func (h *Handler) handleHelp(ctx context.Context, b *bot.Bot, update *models.Upd
 		return
 	}
 
-	msg := "🤖 *Ебанез helper*\n\n"
-	msg += "*Команды:*\n"
-	msg += "/start - Показывает приветственное сообщение\n"
-	msg += "/help - Показывает эту помощь\n"
-	msg += "/gen - Генерирует ответ на основе учтенных сообщений\n"
-	msg += "/clear - Очищает историю чата и сбрасывает обучение\n"
-	msg += "/sticker - Получает случайную стикеровую эмодзи\n"
-	msg += "/stats - Показывает статистику чата\n\n"
-	msg += "Отправьте мне текстовые сообщения и я буду учиться!"
-
 	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    update.Message.Chat.ID,
-		Text:      msg,
-		ParseMode: models.ParseModeMarkdown,
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: topicID(update.Message),
+		Text:            h.helpText(h.locale(ctx, update.Message), update.Message.Chat.Type),
+		ParseMode:       models.ParseModeMarkdown,
 	})
 }
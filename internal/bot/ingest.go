@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"go.uber.org/zap"
+)
+
+// messageText returns the text of the message or the caption of its media
+func messageText(msg *models.Message) string {
+	if msg.Text != "" {
+		return strings.TrimSpace(msg.Text)
+	}
+	return strings.TrimSpace(msg.Caption)
+}
+
+// senderID returns the user, or the chat for anonymous admins and channel
+// posts, that sent the message
+func senderID(msg *models.Message) int64 {
+	if msg.From != nil {
+		return msg.From.ID
+	}
+	if msg.SenderChat != nil {
+		return msg.SenderChat.ID
+	}
+	return 0
+}
+
+// shouldLearn reports whether the message may be used for training, which
+// excludes anything written by bots including the bot's own outputs
+func (h *Handler) shouldLearn(msg *models.Message) bool {
+	if msg.From != nil && msg.From.IsBot {
+		return false
+	}
+
+	if msg.ViaBot != nil && msg.ViaBot.ID == h.me.ID {
+		return false
+	}
+
+	if origin := msg.ForwardOrigin; origin != nil && origin.MessageOriginUser != nil {
+		if origin.MessageOriginUser.SenderUser.IsBot {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newMessage builds the entity to store for the message, dated when it was
+// sent rather than when it is stored
+func newMessage(msg *models.Message, text string) *entity.Message {
+	return &entity.Message{
+		ChatID:    msg.Chat.ID,
+		MessageID: int64(msg.ID),
+		ThreadID:  int64(topicID(msg)),
+		UserID:    senderID(msg),
+		Text:      text,
+		Forwarded: msg.ForwardOrigin != nil,
+		CreatedAt: time.Unix(int64(msg.Date), 0),
+	}
+}
+
+// learn stores the message and trains on it unless it must be skipped
+func (h *Handler) learn(ctx context.Context, msg *models.Message, text string) {
+	const op = "bot/handler.learn"
+
+	if text == "" || strings.HasPrefix(text, "/") || !h.shouldLearn(msg) {
+		return
+	}
+
+	if err := h.service.BotService.HandleMessage(ctx, newMessage(msg, text)); err != nil {
+		h.logger.Error("Failed to handle message", zap.String("op", op), zap.Error(err))
+	}
+}
+
+func (h *Handler) handleEditedMessage(ctx context.Context, msg *models.Message) {
+	const op = "bot/handler.handleEditedMessage"
+
+	text := messageText(msg)
+	if text == "" || strings.HasPrefix(text, "/") || !h.shouldLearn(msg) {
+		return
+	}
+
+	if err := h.service.BotService.HandleEdit(ctx, newMessage(msg, text)); err != nil {
+		h.logger.Error("Failed to handle edited message", zap.String("op", op), zap.Error(err))
+	}
+}
@@ -0,0 +1,273 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// maxInlineEntries bounds the cached answers and chat lists before the
+// expired ones are pruned
+const maxInlineEntries = 1000
+
+// inlineChat is a chat inline results may be generated from, from the
+// chain of the topic the user wrote to last
+type inlineChat struct {
+	id       int64
+	threadID int64
+	title    string
+}
+
+type inlineAnswer struct {
+	results []models.InlineQueryResult
+	expires time.Time
+}
+
+type inlineChats struct {
+	chats   []inlineChat
+	expires time.Time
+}
+
+// inlineCache keeps the answers per user and query, and the chats of every
+// user, so that repeated queries neither generate nor call the API again
+type inlineCache struct {
+	ttl        time.Duration
+	candidates int
+	maxChats   int
+
+	mu      sync.Mutex
+	answers map[string]inlineAnswer
+	chats   map[int64]inlineChats
+}
+
+func newInlineCache(cfg config.InlineConfig) *inlineCache {
+	return &inlineCache{
+		ttl:        cfg.CacheTTL,
+		candidates: cfg.Candidates,
+		maxChats:   cfg.MaxChats,
+		answers:    make(map[string]inlineAnswer),
+		chats:      make(map[int64]inlineChats),
+	}
+}
+
+func (c *inlineCache) answer(key string) ([]models.InlineQueryResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	answer, exists := c.answers[key]
+	if !exists || time.Now().After(answer.expires) {
+		return nil, false
+	}
+	return answer.results, true
+}
+
+func (c *inlineCache) putAnswer(key string, results []models.InlineQueryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.answers) >= maxInlineEntries {
+		for k, answer := range c.answers {
+			if now.After(answer.expires) {
+				delete(c.answers, k)
+			}
+		}
+	}
+	c.answers[key] = inlineAnswer{results: results, expires: now.Add(c.ttl)}
+}
+
+func (c *inlineCache) userChats(userID int64) ([]inlineChat, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chats, exists := c.chats[userID]
+	if !exists || time.Now().After(chats.expires) {
+		return nil, false
+	}
+	return chats.chats, true
+}
+
+func (c *inlineCache) putUserChats(userID int64, chats []inlineChat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.chats) >= maxInlineEntries {
+		for id, cached := range c.chats {
+			if now.After(cached.expires) {
+				delete(c.chats, id)
+			}
+		}
+	}
+	c.chats[userID] = inlineChats{chats: chats, expires: now.Add(c.ttl)}
+}
+
+// parseInlineQuery splits "#chat seed" into the chat filter and the seed,
+// the filter is empty when the query doesn't start with "#"
+func parseInlineQuery(query string) (filter, seed string) {
+	query = strings.TrimSpace(query)
+	if !strings.HasPrefix(query, "#") {
+		return "", query
+	}
+
+	filter, seed, _ = strings.Cut(query[1:], " ")
+	return strings.ToLower(filter), strings.TrimSpace(seed)
+}
+
+// handleInlineQuery answers "@bot [#chat] seed" with texts generated from
+// the chats the user writes to, or only from those whose title contains
+// the filter
+func (h *Handler) handleInlineQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleInlineQuery"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	query := update.InlineQuery
+	if query == nil || query.From == nil {
+		logger.Error("update.InlineQuery or update.InlineQuery.From is nil")
+		return
+	}
+
+	locale := i18n.DefaultLocale
+	if matched, ok := i18n.Match(query.From.LanguageCode); ok {
+		locale = matched
+	}
+
+	key := fmt.Sprintf("%d:%s", query.From.ID, strings.ToLower(strings.TrimSpace(query.Query)))
+	results, cached := h.inline.answer(key)
+	if !cached {
+		if !h.limiter.allow("inline", query.From.ID, query.From.ID) {
+			logger.Info("rate limited", zap.Int64("user_id", query.From.ID))
+			return
+		}
+
+		filter, seed := parseInlineQuery(query.Query)
+		results = h.inlineResults(ctx, query.From.ID, locale, filter, seed)
+		h.inline.putAnswer(key, results)
+	}
+
+	params := &bot.AnswerInlineQueryParams{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     int(h.inline.ttl.Seconds()),
+		IsPersonal:    true,
+	}
+	if len(results) == 0 {
+		params.Button = &models.InlineQueryResultsButton{
+			Text:           i18n.T(locale, "inline.empty"),
+			StartParameter: "inline",
+		}
+	}
+
+	if _, err := b.AnswerInlineQuery(ctx, params); err != nil {
+		logger.Error("failed to answer inline query", zap.Error(err))
+	}
+}
+
+// inlineResults generates the candidates taking turns between the chats,
+// so that every chat is offered before any is offered twice
+func (h *Handler) inlineResults(ctx context.Context, userID int64, locale, filter, seed string) []models.InlineQueryResult {
+	chats := h.userChats(ctx, userID, locale)
+	if filter != "" {
+		matched := make([]inlineChat, 0, len(chats))
+		for _, chat := range chats {
+			if strings.Contains(strings.ToLower(chat.title), filter) {
+				matched = append(matched, chat)
+			}
+		}
+		chats = matched
+	}
+
+	results := make([]models.InlineQueryResult, 0, h.inline.candidates)
+	seen := make(map[string]struct{}, h.inline.candidates)
+
+	for round := 0; round < h.inline.candidates && len(chats) > 0; round++ {
+		active := make([]inlineChat, 0, len(chats))
+		for _, chat := range chats {
+			if len(results) == h.inline.candidates {
+				return results
+			}
+
+			text, err := h.service.BotService.GenerateResponse(ctx, chat.id, chat.threadID, seed)
+			if err != nil {
+				if !errors.Is(err, usecase.ErrNotEnoughData) && !errors.Is(err, usecase.ErrWarmingUp) {
+					h.logger.Error("failed to generate inline result",
+						zap.Int64("chat_id", chat.id),
+						zap.Error(err),
+					)
+				}
+				continue
+			}
+			active = append(active, chat)
+
+			// Stickers and animations can't be sent as the text of a result
+			text = stripMedia(text)
+			if text == "" {
+				continue
+			}
+			if _, exists := seen[text]; exists {
+				continue
+			}
+			seen[text] = struct{}{}
+
+			results = append(results, &models.InlineQueryResultArticle{
+				ID:          strconv.Itoa(len(results)),
+				Title:       chat.title,
+				Description: text,
+				InputMessageContent: &models.InputTextMessageContent{
+					MessageText: text,
+				},
+			})
+		}
+		chats = active
+	}
+
+	return results
+}
+
+// userChats returns the chats the user writes to and is still a member of
+func (h *Handler) userChats(ctx context.Context, userID int64, locale string) []inlineChat {
+	if chats, cached := h.inline.userChats(userID); cached {
+		return chats
+	}
+
+	userChats, err := h.service.BotService.UserChats(ctx, userID, h.inline.maxChats)
+	if err != nil {
+		h.logger.Error("failed to get user chats", zap.Int64("user_id", userID), zap.Error(err))
+		return nil
+	}
+
+	chats := make([]inlineChat, 0, len(userChats))
+	for _, userChat := range userChats {
+		// The private chat with the bot
+		if userChat.ChatID == userID {
+			chats = append(chats, inlineChat{id: userChat.ChatID, title: i18n.T(locale, "inline.private")})
+			continue
+		}
+
+		member, err := h.bot.GetChatMember(ctx, &bot.GetChatMemberParams{ChatID: userChat.ChatID, UserID: userID})
+		if err != nil || member.Type == models.ChatMemberTypeLeft || member.Type == models.ChatMemberTypeBanned {
+			continue
+		}
+
+		chat, err := h.bot.GetChat(ctx, &bot.GetChatParams{ChatID: userChat.ChatID})
+		if err != nil {
+			continue
+		}
+		chats = append(chats, inlineChat{id: userChat.ChatID, threadID: userChat.ThreadID, title: chat.Title})
+	}
+
+	h.inline.putUserChats(userID, chats)
+	return chats
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+)
+
+// scheduledTarget returns a message standing for the chat and topic a
+// scheduled post goes to, so that it can be sent like any answer
+func scheduledTarget(schedule *entity.Schedule) *models.Message {
+	return &models.Message{
+		Chat:            models.Chat{ID: schedule.ChatID},
+		MessageThreadID: int(schedule.ThreadID),
+		IsTopicMessage:  schedule.ThreadID != 0,
+	}
+}
+
+// PostQuote posts a generated "quote of the day" to the chat of the schedule
+func (h *Handler) PostQuote(ctx context.Context, schedule *entity.Schedule) error {
+	quote, err := h.service.BotService.GenerateResponse(ctx, schedule.ChatID, schedule.ThreadID, "")
+	if errors.Is(err, usecase.ErrNotEnoughData) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate quote: %w", err)
+	}
+
+	to := scheduledTarget(schedule)
+	h.sendMessage(ctx, to, h.t(ctx, to, "jobs.quote"))
+	h.sendGenerated(ctx, to, quote, "", false)
+	return nil
+}
+
+// PostDigest posts the statistics of the past week to the chat of the
+// schedule
+func (h *Handler) PostDigest(ctx context.Context, schedule *entity.Schedule) error {
+	stats, err := h.service.BotService.GetChatStats(ctx, schedule.ChatID, entity.StatsPeriodWeek)
+	if err != nil {
+		return fmt.Errorf("failed to get chat stats: %w", err)
+	}
+	if stats.MessageCount == 0 {
+		return nil
+	}
+
+	to := scheduledTarget(schedule)
+	locale := h.locale(ctx, to)
+	h.resolveNames(ctx, schedule.ChatID, locale, stats.TopUsers)
+	h.sendHTML(ctx, to, i18n.T(locale, "jobs.digest")+"\n\n"+formatStats(locale, stats))
+	return nil
+}
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/pkg/markov"
+	"go.uber.org/zap"
+)
+
+// handleLanguage shows the chat language without arguments and lets admins
+// set it or go back to detecting it with "auto"
+func (h *Handler) handleLanguage(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleLanguage"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	lang := strings.ToLower(commandArgs(update.Message.Text))
+	usage := h.t(ctx, update.Message, "language.usage", strings.Join(markov.Languages, "|"))
+
+	if lang == "" {
+		settings, err := h.service.SettingsService.Get(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get chat settings", zap.Error(err))
+			h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.get_failed"))
+			return
+		}
+
+		var state string
+		switch detected := h.service.BotService.ChatLanguage(ctx, chatID); {
+		case settings.Language != "":
+			state = h.t(ctx, update.Message, "language.current", h.languageName(ctx, update.Message, settings.Language))
+		case detected != "":
+			state = h.t(ctx, update.Message, "language.detected", h.languageName(ctx, update.Message, detected))
+		default:
+			state = h.t(ctx, update.Message, "language.auto")
+		}
+		h.sendMessage(ctx, update.Message, state+"\n\n"+usage)
+		return
+	}
+
+	if lang == "auto" {
+		lang = ""
+	} else if !markov.IsLanguage(lang) {
+		h.sendMessage(ctx, update.Message, usage)
+		return
+	}
+
+	err := h.service.SettingsService.Update(ctx, chatID, func(settings *entity.ChatSettings) {
+		settings.Language = lang
+	})
+	if err != nil {
+		logger.Error("Failed to update chat settings", zap.Error(err))
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.save_failed"))
+		return
+	}
+
+	if lang == "" {
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "language.reset"))
+		return
+	}
+	h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "language.set", h.languageName(ctx, update.Message, lang)))
+}
+
+// languageName returns the name of the language in the locale of the chat
+func (h *Handler) languageName(ctx context.Context, msg *models.Message, lang string) string {
+	return h.t(ctx, msg, "language."+lang)
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/i18n"
+)
+
+// locale returns the locale of texts sent in reply to the message: the
+// language set for the chat, then the language of the sender's Telegram
+// client, then the one detected from the chat's messages
+func (h *Handler) locale(ctx context.Context, msg *models.Message) string {
+	settings, err := h.service.SettingsService.Get(ctx, msg.Chat.ID)
+	if err == nil && settings.Language != "" {
+		return i18n.Locale(settings.Language)
+	}
+
+	if msg.From != nil && msg.From.LanguageCode != "" {
+		if locale, ok := i18n.Match(msg.From.LanguageCode); ok {
+			return locale
+		}
+	}
+
+	return i18n.Locale(h.service.BotService.ChatLanguage(ctx, msg.Chat.ID))
+}
+
+// t returns the text of the catalog in the locale of the message
+func (h *Handler) t(ctx context.Context, msg *models.Message, key string, args ...any) string {
+	return i18n.T(h.locale(ctx, msg), key, args...)
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) handleAnimationMessage(ctx context.Context, msg *models.Message) {
+	const op = "bot/handler.handleAnimationMessage"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	animation := msg.Animation
+
+	err := h.service.BotService.HandleAnimation(ctx, &entity.Animation{
+		ChatID:       msg.Chat.ID,
+		FileID:       animation.FileID,
+		FileUniqueID: animation.FileUniqueID,
+	})
+	if err != nil {
+		logger.Error("Failed to handle animation", zap.Error(err))
+		return
+	}
+
+	h.learnMedia(ctx, msg, entity.MediaAnimation, animation.FileUniqueID)
+}
+
+// learnMedia stores the media as a token of the chat's message stream, so
+// that the chain learns when it is sent
+func (h *Handler) learnMedia(ctx context.Context, msg *models.Message, kind entity.MediaKind, fileUniqueID string) {
+	const op = "bot/handler.learnMedia"
+
+	if fileUniqueID == "" || !h.shouldLearn(msg) {
+		return
+	}
+
+	message := newMessage(msg, entity.MediaToken(kind, fileUniqueID))
+	message.Source = entity.MessageSource(kind)
+
+	if err := h.service.BotService.HandleMessage(ctx, message); err != nil {
+		h.logger.Error("Failed to handle media message", zap.String("op", op), zap.Error(err))
+	}
+}
+
+// sendAnimation sends the animation to the chat and forum topic the message
+// came from, quoting the message if reply is set
+func (h *Handler) sendAnimation(ctx context.Context, to *models.Message, fileID string, reply bool) {
+	params := &bot.SendAnimationParams{
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Animation:       &models.InputFileString{Data: fileID},
+	}
+	if reply {
+		params.ReplyParameters = replyTo(to)
+	}
+
+	h.bot.SendAnimation(ctx, params)
+	h.limiter.markSent(to.Chat.ID)
+}
+
+// sendGenerated sends a generated response, splitting it into text messages
+// and the stickers and animations its media tokens stand for. Only the
+// first message quotes the original one if reply is set. A response of text
+// alone is sent as a single message users can rate.
+func (h *Handler) sendGenerated(ctx context.Context, to *models.Message, response, seed string, reply bool) {
+	const op = "bot/handler.sendGenerated"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if !hasMedia(response) {
+		h.sendRated(ctx, to, response, seed, reply)
+		return
+	}
+
+	var words []string
+
+	flush := func() {
+		if len(words) == 0 {
+			return
+		}
+		if reply {
+			h.replyMessage(ctx, to, strings.Join(words, " "))
+		} else {
+			h.sendMessage(ctx, to, strings.Join(words, " "))
+		}
+		words = nil
+		reply = false
+	}
+
+	for _, token := range strings.Fields(response) {
+		kind, fileUniqueID, ok := entity.ParseMediaToken(token)
+		if !ok {
+			words = append(words, token)
+			continue
+		}
+
+		fileID, err := h.service.BotService.GetMediaFileID(ctx, to.Chat.ID, kind, fileUniqueID)
+		if err != nil {
+			logger.Warn("Failed to resolve media token", zap.String("token", token), zap.Error(err))
+			continue
+		}
+
+		flush()
+
+		switch kind {
+		case entity.MediaSticker:
+			h.sendSticker(ctx, to, fileID, reply)
+		case entity.MediaAnimation:
+			h.sendAnimation(ctx, to, fileID, reply)
+		}
+		reply = false
+	}
+
+	flush()
+}
+
+// hasMedia reports whether the response contains media tokens
+func hasMedia(response string) bool {
+	for _, token := range strings.Fields(response) {
+		if entity.IsMediaToken(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripMedia drops the media tokens of the response
+func stripMedia(response string) string {
+	words := strings.Fields(response)
+	text := words[:0]
+	for _, word := range words {
+		if !entity.IsMediaToken(word) {
+			text = append(text, word)
+		}
+	}
+	return strings.Join(text, " ")
+}
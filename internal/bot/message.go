@@ -10,11 +10,57 @@ import (
 	"go.uber.org/zap"
 )
 
-func (h *Handler) sendMessage(ctx context.Context, chatID int64, text string) {
+// stickerReplyPercent is the share of auto replies tried as stickers
+const stickerReplyPercent = 30
+
+// sendMessage sends text to the chat and forum topic the message came from
+func (h *Handler) sendMessage(ctx context.Context, to *models.Message, text string) {
+	h.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Text:            text,
+	})
+	h.limiter.markSent(to.Chat.ID)
+}
+
+// sendHTML sends text formatted with Telegram HTML
+func (h *Handler) sendHTML(ctx context.Context, to *models.Message, text string) {
 	h.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: chatID,
-		Text:   text,
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Text:            text,
+		ParseMode:       models.ParseModeHTML,
+		LinkPreviewOptions: &models.LinkPreviewOptions{
+			IsDisabled: bot.True(),
+		},
 	})
+	h.limiter.markSent(to.Chat.ID)
+}
+
+// replyMessage sends text as a reply quoting the message
+func (h *Handler) replyMessage(ctx context.Context, to *models.Message, text string) {
+	h.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Text:            text,
+		ReplyParameters: replyTo(to),
+	})
+	h.limiter.markSent(to.Chat.ID)
+}
+
+// topicID returns the forum topic of the message, zero outside of topics
+func topicID(msg *models.Message) int {
+	if msg.IsTopicMessage {
+		return msg.MessageThreadID
+	}
+	return 0
+}
+
+func replyTo(msg *models.Message) *models.ReplyParameters {
+	return &models.ReplyParameters{
+		MessageID:                msg.ID,
+		AllowSendingWithoutReply: true,
+	}
 }
 
 func (h *Handler) handleTextMessage(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -22,24 +68,44 @@ func (h *Handler) handleTextMessage(ctx context.Context, b *bot.Bot, update *mod
 
 	logger := h.logger.With(zap.String("op", op))
 
-	if update.Message == nil || update.Message.Text == "" {
-		logger.Error("update.Message is nil or update.Message.Text is empty")
+	if update.Message == nil {
+		logger.Error("update.Message is nil")
+		return
+	}
+
+	text := messageText(update.Message)
+	if text == "" || strings.HasPrefix(text, "/") {
 		return
 	}
 
 	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
-	text := strings.TrimSpace(update.Message.Text)
+	userID := senderID(update.Message)
 
-	if strings.HasPrefix(text, "/") {
+	h.learn(ctx, update.Message, text)
+
+	settings, err := h.service.SettingsService.Get(ctx, chatID)
+	if err != nil {
+		logger.Error("Failed to get chat settings", zap.Error(err))
 		return
 	}
 
-	if err := h.service.BotService.HandleMessage(ctx, chatID, userID, text); err != nil {
-		logger.Error("Failed to handle message", zap.Error(err))
+	if seed, ok := h.addressedSeed(update.Message, text, settings.WakeWords); ok {
+		if !h.limiter.allow("mention", chatID, userID) {
+			return
+		}
+		if response, ok := h.generate(ctx, update.Message, seed, false); ok {
+			h.sendGenerated(ctx, update.Message, response, seed, true)
+		}
+		return
 	}
 
-	if rand.Intn(100) > 70 {
-		h.handleGenerate(ctx, b, update)
+	if rand.Intn(100) > 70 && h.limiter.allowAutoReply(chatID) {
+		// Now and then answer with a sticker the chat uses in such context
+		if rand.Intn(100) < stickerReplyPercent && h.replySticker(ctx, update.Message, text) {
+			return
+		}
+		if response, ok := h.generate(ctx, update.Message, "", false); ok {
+			h.sendGenerated(ctx, update.Message, response, "", true)
+		}
 	}
 }
@@ -1,15 +0,0 @@
-package bot
-
-import (
-	"context"
-
-	"github.com/go-telegram/bot"
-	"github.com/go-telegram/bot/models"
-)
-
-func (h *Handler) Middleware(next bot.HandlerFunc) bot.HandlerFunc {
-	return func(ctx context.Context, bot *bot.Bot, update *models.Update) {
-		h.defaultHandler(ctx, bot, update)
-		next(ctx, bot, update)
-	}
-}
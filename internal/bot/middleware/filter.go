@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// RequireMessage drops updates without a new or edited message or a
+// channel post, except for inline and callback queries and reactions
+func RequireMessage() bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if updateMessage(update) == nil && update.InlineQuery == nil &&
+				update.CallbackQuery == nil && update.MessageReaction == nil {
+				updatesDropped.Add("no_message", 1)
+				return
+			}
+
+			next(ctx, b, update)
+		}
+	}
+}
+
+// Ignore drops updates coming from the listed chats or users
+func Ignore(chatIDs, userIDs []int64) bot.Middleware {
+	chats := make(map[int64]struct{}, len(chatIDs))
+	for _, id := range chatIDs {
+		chats[id] = struct{}{}
+	}
+
+	users := make(map[int64]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		users[id] = struct{}{}
+	}
+
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			chatID, userID := updateSource(update)
+
+			if _, ignored := chats[chatID]; ignored && chatID != 0 {
+				updatesDropped.Add("ignored_chat", 1)
+				return
+			}
+			if _, ignored := users[userID]; ignored && userID != 0 {
+				updatesDropped.Add("ignored_user", 1)
+				return
+			}
+
+			next(ctx, b, update)
+		}
+	}
+}
+
+// SkipBots drops messages authored by other bots
+func SkipBots() bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if msg := updateMessage(update); msg != nil && msg.From != nil && msg.From.IsBot {
+				updatesDropped.Add("bot_author", 1)
+				return
+			}
+
+			next(ctx, b, update)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// Logging logs every processed update with its handling time
+func Logging(logger *zap.Logger) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			start := time.Now()
+			next(ctx, b, update)
+
+			chatID, userID := updateSource(update)
+			logger.Debug("update handled",
+				zap.Int64("update_id", update.ID),
+				zap.String("kind", updateKind(update)),
+				zap.Int64("chat_id", chatID),
+				zap.Int64("user_id", userID),
+				zap.Duration("duration", time.Since(start)),
+			)
+		}
+	}
+}
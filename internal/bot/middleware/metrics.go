@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+var (
+	updates         = expvar.NewMap("bot_updates_total")
+	updatesDropped  = expvar.NewMap("bot_updates_dropped_total")
+	handlerDuration = expvar.NewMap("bot_handler_duration_ms_total")
+	panics          = expvar.NewInt("bot_handler_panics_total")
+)
+
+// Metrics counts updates by kind and accumulates handling time
+func Metrics() bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			kind := updateKind(update)
+			updates.Add(kind, 1)
+
+			start := time.Now()
+			next(ctx, b, update)
+			handlerDuration.Add(kind, time.Since(start).Milliseconds())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package middleware contains composable bot.Middleware implementations
+// applied to every update via bot.WithMiddlewares.
+package middleware
+
+import (
+	"github.com/go-telegram/bot/models"
+)
+
+// updateKind returns a short name of the payload carried by the update
+func updateKind(update *models.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.MessageReaction != nil:
+		return "message_reaction"
+	default:
+		return "other"
+	}
+}
+
+// updateMessage returns the message carried by the update, whether it is a
+// new, an edited message or a channel post
+func updateMessage(update *models.Update) *models.Message {
+	switch {
+	case update.Message != nil:
+		return update.Message
+	case update.EditedMessage != nil:
+		return update.EditedMessage
+	case update.ChannelPost != nil:
+		return update.ChannelPost
+	default:
+		return update.EditedChannelPost
+	}
+}
+
+// updateSource returns the chat and user the update originates from,
+// zeroes are returned when they are unknown
+func updateSource(update *models.Update) (chatID, userID int64) {
+	if msg := updateMessage(update); msg != nil {
+		chatID = msg.Chat.ID
+		if msg.From != nil {
+			userID = msg.From.ID
+		}
+	}
+	if update.InlineQuery != nil && update.InlineQuery.From != nil {
+		userID = update.InlineQuery.From.ID
+	}
+	if update.MessageReaction != nil {
+		chatID = update.MessageReaction.Chat.ID
+		if update.MessageReaction.User != nil {
+			userID = update.MessageReaction.User.ID
+		}
+	}
+	if update.CallbackQuery != nil {
+		userID = update.CallbackQuery.From.ID
+		if msg := update.CallbackQuery.Message.Message; msg != nil {
+			chatID = msg.Chat.ID
+		}
+	}
+
+	return chatID, userID
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// Recover stops a panicking handler from crashing the whole bot
+func Recover(logger *zap.Logger) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			defer func() {
+				if r := recover(); r != nil {
+					panics.Add(1)
+					logger.Error("handler panicked",
+						zap.Int64("update_id", update.ID),
+						zap.String("kind", updateKind(update)),
+						zap.Any("panic", r),
+						zap.Stack("stack"),
+					)
+				}
+			}()
+
+			next(ctx, b, update)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"go.uber.org/zap"
+)
+
+// quoteSearchLimit is the number of quotes /quote search shows
+const quoteSearchLimit = 5
+
+// handleQuote saves the message replied to into the quote book, or shows
+// quotes with "random" and "search <text>"
+func (h *Handler) handleQuote(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleQuote"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil {
+		logger.Error("update.Message is nil")
+		return
+	}
+
+	msg := update.Message
+	locale := h.locale(ctx, msg)
+
+	action, term, _ := strings.Cut(commandArgs(msg.Text), " ")
+	switch strings.ToLower(action) {
+	case "":
+		h.saveQuote(ctx, msg, locale)
+	case "random":
+		h.randomQuote(ctx, msg, locale)
+	case "search":
+		h.searchQuotes(ctx, msg, locale, strings.TrimSpace(term))
+	default:
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.usage"))
+	}
+}
+
+func (h *Handler) saveQuote(ctx context.Context, msg *models.Message, locale string) {
+	const op = "bot/handler.saveQuote"
+
+	// Every message of a forum topic replies to the message opening it
+	quoted := msg.ReplyToMessage
+	if quoted == nil || quoted.ForumTopicCreated != nil {
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.reply_needed"))
+		return
+	}
+
+	text := messageText(quoted)
+	if text == "" {
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.no_text"))
+		return
+	}
+
+	created, err := h.service.QuoteService.Save(ctx, &entity.Quote{
+		ChatID:    msg.Chat.ID,
+		ThreadID:  int64(topicID(msg)),
+		MessageID: int64(quoted.ID),
+		UserID:    senderID(quoted),
+		Author:    authorName(quoted),
+		Text:      text,
+		SavedBy:   senderID(msg),
+		SentAt:    time.Unix(int64(quoted.Date), 0),
+	})
+	// The quote may be saved even if learning it failed
+	if err != nil {
+		h.logger.Error("Failed to save quote", zap.String("op", op), zap.Error(err))
+	}
+
+	switch {
+	case created:
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.saved"))
+	case err != nil:
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.failed"))
+	default:
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.exists"))
+	}
+}
+
+func (h *Handler) randomQuote(ctx context.Context, msg *models.Message, locale string) {
+	const op = "bot/handler.randomQuote"
+
+	quote, err := h.service.QuoteService.Random(ctx, msg.Chat.ID)
+	if err != nil {
+		h.logger.Error("Failed to get random quote", zap.String("op", op), zap.Error(err))
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.failed"))
+		return
+	}
+	if quote == nil {
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.empty"))
+		return
+	}
+
+	h.sendHTML(ctx, msg, h.formatQuote(ctx, locale, quote))
+}
+
+func (h *Handler) searchQuotes(ctx context.Context, msg *models.Message, locale, term string) {
+	const op = "bot/handler.searchQuotes"
+
+	if term == "" {
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.usage"))
+		return
+	}
+
+	quotes, err := h.service.QuoteService.Search(ctx, msg.Chat.ID, term, quoteSearchLimit)
+	if err != nil {
+		h.logger.Error("Failed to search quotes", zap.String("op", op), zap.Error(err))
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.failed"))
+		return
+	}
+	if len(quotes) == 0 {
+		h.sendMessage(ctx, msg, i18n.T(locale, "quote.not_found", term))
+		return
+	}
+
+	formatted := make([]string, 0, len(quotes))
+	for _, quote := range quotes {
+		formatted = append(formatted, h.formatQuote(ctx, locale, quote))
+	}
+	h.sendHTML(ctx, msg, strings.Join(formatted, "\n\n"))
+}
+
+// formatQuote shows the quote with its author and date, the author is
+// looked up in the chat if the quote has no name stored
+func (h *Handler) formatQuote(ctx context.Context, locale string, quote *entity.Quote) string {
+	author := quote.Author
+	if author == "" {
+		author = h.userName(ctx, quote.ChatID, locale, quote.UserID)
+	}
+
+	return i18n.T(locale, "quote.format",
+		html.EscapeString(quote.Text),
+		html.EscapeString(author),
+		quote.SentAt.In(h.location).Format("02.01.2006"),
+	)
+}
+
+// authorName returns the name of the user, or the chat, that sent the
+// message
+func authorName(msg *models.Message) string {
+	switch {
+	case msg.SenderChat != nil:
+		return msg.SenderChat.Title
+	case msg.From != nil:
+		return strings.TrimSpace(msg.From.FirstName + " " + msg.From.LastName)
+	default:
+		return ""
+	}
+}
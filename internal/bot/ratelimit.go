@@ -0,0 +1,225 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/config"
+	"go.uber.org/zap"
+)
+
+// maxBuckets bounds the number of idle buckets kept before they are pruned
+const maxBuckets = 10000
+
+// tokenBucket is a classic token bucket refilled continuously over time
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketLimiter keeps one token bucket per key
+type bucketLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	refill   float64 // tokens per second
+	buckets  map[string]*tokenBucket
+}
+
+func newBucketLimiter(perMinute int) *bucketLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	return &bucketLimiter{
+		capacity: float64(perMinute),
+		refill:   float64(perMinute) / 60,
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+// available reports whether the bucket of the key has a token left, without
+// taking it
+func (l *bucketLimiter) available(key string, now time.Time) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.bucket(key, now).tokens >= 1
+}
+
+// take takes a token from the bucket of the key
+func (l *bucketLimiter) take(key string, now time.Time) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.bucket(key, now).tokens--
+}
+
+// bucket returns the bucket of the key refilled up to now
+func (l *bucketLimiter) bucket(key string, now time.Time) *tokenBucket {
+	b, exists := l.buckets[key]
+	if !exists {
+		if len(l.buckets) >= maxBuckets {
+			l.prune(now)
+		}
+		b = &tokenBucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.refill
+	if b.tokens > l.capacity {
+		b.tokens = l.capacity
+	}
+	b.last = now
+
+	return b
+}
+
+// prune drops buckets that have been refilled completely, they are
+// indistinguishable from fresh ones
+func (l *bucketLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.refill >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimiter combines per-user, per-chat and per-command limits with
+// throttled cooldown notices and a minimum interval between bot messages
+type rateLimiter struct {
+	user     *bucketLimiter
+	chat     *bucketLimiter
+	commands map[string]*bucketLimiter
+	// takeMu makes checking and taking the tokens of an update atomic
+	takeMu sync.Mutex
+
+	noticeInterval    time.Duration
+	autoReplyInterval time.Duration
+
+	mu          sync.Mutex
+	notices     map[string]time.Time
+	lastMessage map[int64]time.Time
+}
+
+func newRateLimiter(cfg config.RateLimitConfig) *rateLimiter {
+	commands := make(map[string]*bucketLimiter, len(cfg.Commands))
+	for command, perMinute := range cfg.Commands {
+		commands[command] = newBucketLimiter(perMinute)
+	}
+
+	return &rateLimiter{
+		user:              newBucketLimiter(cfg.UserPerMinute),
+		chat:              newBucketLimiter(cfg.ChatPerMinute),
+		commands:          commands,
+		noticeInterval:    cfg.NoticeInterval,
+		autoReplyInterval: cfg.AutoReplyInterval,
+		notices:           make(map[string]time.Time),
+		lastMessage:       make(map[int64]time.Time),
+	}
+}
+
+// allow takes a token from the command, user and chat buckets if all of
+// them have one, a rejected update uses up none of them
+func (r *rateLimiter) allow(command string, chatID, userID int64) bool {
+	now := time.Now()
+	userKey := fmt.Sprintf("%d:%d", chatID, userID)
+	chatKey := fmt.Sprint(chatID)
+	commandLimiter := r.commands[command]
+
+	r.takeMu.Lock()
+	defer r.takeMu.Unlock()
+
+	if !commandLimiter.available(userKey, now) || !r.user.available(userKey, now) || !r.chat.available(chatKey, now) {
+		return false
+	}
+
+	commandLimiter.take(userKey, now)
+	r.user.take(userKey, now)
+	r.chat.take(chatKey, now)
+	return true
+}
+
+// shouldNotify reports whether a cooldown notice may be sent to the user,
+// so that the notices themselves cannot be used to flood the chat
+func (r *rateLimiter) shouldNotify(chatID, userID int64) bool {
+	now := time.Now()
+	key := fmt.Sprintf("%d:%d", chatID, userID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if last, exists := r.notices[key]; exists && now.Sub(last) < r.noticeInterval {
+		return false
+	}
+
+	if len(r.notices) >= maxBuckets {
+		for k, last := range r.notices {
+			if now.Sub(last) >= r.noticeInterval {
+				delete(r.notices, k)
+			}
+		}
+	}
+
+	r.notices[key] = now
+	return true
+}
+
+// markSent records that the bot has just posted to the chat
+func (r *rateLimiter) markSent(chatID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastMessage[chatID] = time.Now()
+}
+
+// allowAutoReply reports whether enough time has passed since the bot's
+// last message in the chat
+func (r *rateLimiter) allowAutoReply(chatID int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last, exists := r.lastMessage[chatID]
+	return !exists || time.Since(last) >= r.autoReplyInterval
+}
+
+// RateLimit rejects updates exceeding the limits configured for command
+func (h *Handler) RateLimit(command string) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if update.Message == nil || update.Message.From == nil {
+				next(ctx, b, update)
+				return
+			}
+
+			chatID := update.Message.Chat.ID
+			userID := update.Message.From.ID
+
+			if h.limiter.allow(command, chatID, userID) {
+				next(ctx, b, update)
+				return
+			}
+
+			h.logger.Info("rate limited",
+				zap.String("command", command),
+				zap.Int64("chat_id", chatID),
+				zap.Int64("user_id", userID),
+			)
+
+			if h.limiter.shouldNotify(chatID, userID) {
+				h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "ratelimit.notice"))
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/malinatrash/egonez/config"
+)
+
+// TestRateLimitRejectedKeepsTokens makes sure an update rejected by the user
+// bucket doesn't use up the command bucket
+func TestRateLimitRejectedKeepsTokens(t *testing.T) {
+	limiter := newRateLimiter(config.RateLimitConfig{
+		UserPerMinute: 2,
+		Commands:      map[string]int{"gen": 3},
+	})
+
+	for i := range 2 {
+		if !limiter.allow("top", 1, 1) {
+			t.Fatalf("update %d rejected, want allowed", i)
+		}
+	}
+	for range 5 {
+		if limiter.allow("gen", 1, 1) {
+			t.Fatal("update allowed over the user limit")
+		}
+	}
+
+	if tokens := limiter.commands["gen"].buckets["1:1"].tokens; tokens < 3 {
+		t.Errorf("gen bucket has %v tokens, want 3", tokens)
+	}
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"go.uber.org/zap"
+)
+
+// positiveReactions and negativeReactions are the emoji counted as a like
+// and a dislike, any other reaction is neutral
+var (
+	positiveReactions = map[string]struct{}{
+		"👍": {}, "❤": {}, "🔥": {}, "🥰": {}, "👏": {}, "😁": {}, "🤩": {}, "🎉": {},
+		"💯": {}, "🤣": {}, "❤‍🔥": {}, "😍": {}, "🏆": {}, "🤗": {}, "⚡": {}, "😘": {},
+		"🆒": {}, "💘": {}, "🍾": {}, "👌": {}, "😇": {}, "🫡": {}, "🐳": {},
+	}
+	negativeReactions = map[string]struct{}{
+		"👎": {}, "💩": {}, "🤮": {}, "🤡": {}, "🥱": {}, "😡": {}, "🤬": {}, "🖕": {}, "😴": {},
+	}
+)
+
+// reactionValue returns the vote the reactions of a user stand for: 1 if
+// they are mostly positive, -1 if mostly negative and zero otherwise
+func reactionValue(reactions []models.ReactionType) int {
+	sum := 0
+	for _, reaction := range reactions {
+		switch {
+		case reaction.ReactionTypePaid != nil:
+			sum++
+		case reaction.ReactionTypeEmoji != nil:
+			if _, ok := positiveReactions[reaction.ReactionTypeEmoji.Emoji]; ok {
+				sum++
+			}
+			if _, ok := negativeReactions[reaction.ReactionTypeEmoji.Emoji]; ok {
+				sum--
+			}
+		}
+	}
+
+	return min(max(sum, -1), 1)
+}
+
+// handleReaction counts reactions on generated messages as votes, a user
+// has a single vote per message whatever the number of reactions
+func (h *Handler) handleReaction(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleReaction"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	reaction := update.MessageReaction
+	// Anonymous reactions come without a user and can't be told apart
+	if reaction == nil || reaction.User == nil || reaction.User.IsBot {
+		return
+	}
+
+	chatID := reaction.Chat.ID
+	generation, err := h.service.FeedbackService.Get(ctx, chatID, int64(reaction.MessageID))
+	if err != nil {
+		logger.Error("failed to get generation", zap.Error(err))
+		return
+	}
+	if generation == nil {
+		return
+	}
+
+	changed, err := h.service.FeedbackService.React(ctx, generation, reaction.User.ID, reactionValue(reaction.NewReaction))
+	if err != nil {
+		logger.Error("failed to record reaction", zap.Error(err))
+		return
+	}
+	if !changed || generation.Deleted {
+		return
+	}
+
+	logger.Debug("reaction recorded",
+		zap.Int64("chat_id", chatID),
+		zap.Int64("generation_id", generation.ID),
+		zap.String("seed", generation.Seed),
+		zap.Int("likes", generation.Likes),
+		zap.Int("dislikes", generation.Dislikes),
+	)
+
+	_, err = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      chatID,
+		MessageID:   reaction.MessageID,
+		ReplyMarkup: feedbackKeyboard(generation),
+	})
+	if err != nil {
+		logger.Error("failed to update feedback buttons", zap.Error(err))
+	}
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) handleRetention(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleRetention"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(commandArgs(update.Message.Text))
+	locale := h.locale(ctx, update.Message)
+	usage := i18n.T(locale, "retention.usage")
+
+	if len(args) == 0 {
+		settings, err := h.service.SettingsService.Get(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get chat settings", zap.Error(err))
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "settings.get_failed"))
+			return
+		}
+		h.sendMessage(ctx, update.Message, formatRetention(locale, settings)+"\n\n"+usage)
+		return
+	}
+
+	var apply func(settings *entity.ChatSettings)
+	switch {
+	case len(args) == 1 && args[0] == "off":
+		apply = func(settings *entity.ChatSettings) {
+			settings.RetentionDays = 0
+			settings.RetentionMessages = 0
+		}
+	case len(args) == 2 && (args[0] == "days" || args[0] == "messages"):
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "retention.bad_number")+"\n\n"+usage)
+			return
+		}
+		if args[0] == "days" {
+			apply = func(settings *entity.ChatSettings) { settings.RetentionDays = n }
+		} else {
+			apply = func(settings *entity.ChatSettings) { settings.RetentionMessages = n }
+		}
+	default:
+		h.sendMessage(ctx, update.Message, usage)
+		return
+	}
+
+	if err := h.service.SettingsService.Update(ctx, chatID, apply); err != nil {
+		logger.Error("Failed to update chat settings", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "settings.save_failed"))
+		return
+	}
+
+	deleted, err := h.service.RetentionService.Apply(ctx, chatID)
+	if err != nil {
+		logger.Error("Failed to apply retention policy", zap.Error(err))
+	}
+
+	settings, err := h.service.SettingsService.Get(ctx, chatID)
+	if err != nil {
+		logger.Error("Failed to get chat settings", zap.Error(err))
+		return
+	}
+
+	msg := "✅ " + formatRetention(locale, settings)
+	if deleted > 0 {
+		msg += "\n" + i18n.N(locale, "retention.deleted", int(deleted), deleted)
+	}
+	h.sendMessage(ctx, update.Message, msg)
+}
+
+func formatRetention(locale string, settings *entity.ChatSettings) string {
+	if !settings.HasRetention() {
+		return i18n.T(locale, "retention.forever")
+	}
+
+	var limits []string
+	if settings.RetentionDays > 0 {
+		limits = append(limits, i18n.N(locale, "retention.days", settings.RetentionDays, settings.RetentionDays))
+	}
+	if settings.RetentionMessages > 0 {
+		limits = append(limits, i18n.N(locale, "retention.messages", settings.RetentionMessages, settings.RetentionMessages))
+	}
+
+	return i18n.T(locale, "retention.kept", strings.Join(limits, i18n.T(locale, "retention.and")))
+}
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) handleRuleAdd(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleRuleAdd"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	locale := h.locale(ctx, update.Message)
+	rule, err := parseRule(locale, commandArgs(update.Message.Text))
+	if err != nil {
+		h.sendMessage(ctx, update.Message, "❌ "+err.Error()+"\n\n"+i18n.T(locale, "rule.usage"))
+		return
+	}
+	rule.ChatID = chatID
+	rule.CreatedBy = update.Message.From.ID
+
+	if err := h.service.RuleService.AddRule(ctx, rule); err != nil {
+		if errors.Is(err, usecase.ErrInvalidRule) {
+			h.sendMessage(ctx, update.Message, "❌ "+err.Error()+"\n\n"+i18n.T(locale, "rule.usage"))
+			return
+		}
+		if errors.Is(err, usecase.ErrTooManyRules) {
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.too_many"))
+			return
+		}
+		logger.Error("Failed to add rule", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.save_failed"))
+		return
+	}
+
+	h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.added", rule.ID))
+}
+
+func (h *Handler) handleRules(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleRules"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil {
+		logger.Error("update.Message is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	locale := h.locale(ctx, update.Message)
+	rules, err := h.service.RuleService.ListRules(ctx, chatID)
+	if err != nil {
+		logger.Error("Failed to list rules", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.list_failed"))
+		return
+	}
+
+	if len(rules) == 0 {
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.empty")+"\n\n"+i18n.T(locale, "rule.usage"))
+		return
+	}
+
+	msg := i18n.T(locale, "rule.title") + "\n\n"
+	for _, rule := range rules {
+		msg += formatRule(locale, rule) + "\n"
+	}
+
+	h.sendMessage(ctx, update.Message, msg)
+}
+
+func (h *Handler) handleRuleDelete(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleRuleDelete"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	locale := h.locale(ctx, update.Message)
+	id, err := strconv.ParseInt(strings.TrimPrefix(commandArgs(update.Message.Text), "#"), 10, 64)
+	if err != nil {
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.delete_usage"))
+		return
+	}
+
+	deleted, err := h.service.RuleService.DeleteRule(ctx, chatID, id)
+	if err != nil {
+		logger.Error("Failed to delete rule", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.delete_failed"))
+		return
+	}
+
+	if !deleted {
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.not_found", id))
+		return
+	}
+
+	h.sendMessage(ctx, update.Message, i18n.T(locale, "rule.deleted", id))
+}
+
+// applyRules answers the message according to the first matching rule
+func (h *Handler) applyRules(ctx context.Context, msg *models.Message) {
+	const op = "bot/handler.applyRules"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	match, err := h.service.RuleService.Match(ctx, msg.Chat.ID, text, messageKinds(msg)...)
+	if err != nil {
+		logger.Error("Failed to match rules", zap.Error(err))
+		return
+	}
+	if match == nil {
+		return
+	}
+
+	logger.Info("Rule fired", zap.Int64("rule_id", match.Rule.ID), zap.Int64("chat_id", msg.Chat.ID))
+
+	chatID := msg.Chat.ID
+	rule := match.Rule
+
+	switch rule.ResponseType {
+	case entity.RuleResponseText:
+		h.replyMessage(ctx, msg, rule.Response)
+	case entity.RuleResponseSticker:
+		h.sendSticker(ctx, msg, rule.Response, true)
+	case entity.RuleResponseGenerate:
+		seed := rule.Response
+		if seed == "" {
+			seed = match.Seed
+		}
+		text, err := h.service.BotService.GenerateResponse(ctx, chatID, int64(topicID(msg)), seed)
+		if err != nil {
+			logger.Error("Failed to generate response", zap.Error(err))
+			return
+		}
+		h.sendGenerated(ctx, msg, text, seed, true)
+	case entity.RuleResponseReaction:
+		_, err := h.bot.SetMessageReaction(ctx, &bot.SetMessageReactionParams{
+			ChatID:    chatID,
+			MessageID: msg.ID,
+			Reaction: []models.ReactionType{{
+				Type:              models.ReactionTypeTypeEmoji,
+				ReactionTypeEmoji: &models.ReactionTypeEmoji{Emoji: rule.Response},
+			}},
+		})
+		if err != nil {
+			logger.Error("Failed to set reaction", zap.Error(err))
+		}
+	}
+}
+
+// messageKinds lists the rule match types describing the message content
+func messageKinds(msg *models.Message) []entity.RuleMatchType {
+	var kinds []entity.RuleMatchType
+
+	if msg.Voice != nil {
+		kinds = append(kinds, entity.RuleMatchVoice)
+	}
+	if msg.Video != nil || msg.VideoNote != nil {
+		kinds = append(kinds, entity.RuleMatchVideo)
+	}
+	if msg.Sticker != nil {
+		kinds = append(kinds, entity.RuleMatchSticker)
+	}
+	if msg.BoostAdded != nil {
+		kinds = append(kinds, entity.RuleMatchBoost)
+	}
+
+	return kinds
+}
+
+// parseRule parses "<type>[:<pattern>] | <response>[:<value>] [| <probability>] [| <cooldown>]",
+// a pattern or value containing "|" is put in double quotes
+func parseRule(locale, args string) (*entity.Rule, error) {
+	parts, ok := splitRule(args)
+	if !ok || len(parts) < 2 || len(parts) > 4 {
+		return nil, errors.New(i18n.T(locale, "rule.bad_format"))
+	}
+
+	matchType, pattern, _ := strings.Cut(strings.TrimSpace(parts[0]), ":")
+	responseType, response, _ := strings.Cut(strings.TrimSpace(parts[1]), ":")
+
+	rule := &entity.Rule{
+		MatchType:    entity.RuleMatchType(strings.ToLower(strings.TrimSpace(matchType))),
+		Pattern:      unquote(strings.TrimSpace(pattern)),
+		ResponseType: entity.RuleResponseType(strings.ToLower(strings.TrimSpace(responseType))),
+		Response:     unquote(strings.TrimSpace(response)),
+		Probability:  1,
+	}
+
+	if len(parts) > 2 {
+		p, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil {
+			return nil, errors.New(i18n.T(locale, "rule.bad_probability", strings.TrimSpace(parts[2])))
+		}
+		rule.Probability = p
+	}
+
+	if len(parts) > 3 {
+		cd, err := time.ParseDuration(strings.TrimSpace(parts[3]))
+		if err != nil {
+			return nil, errors.New(i18n.T(locale, "rule.bad_cooldown", strings.TrimSpace(parts[3])))
+		}
+		rule.Cooldown = cd
+	}
+
+	return rule, nil
+}
+
+// splitRule splits the rule at every "|" outside of double quotes, it
+// reports false if a quote isn't closed
+func splitRule(args string) ([]string, bool) {
+	var (
+		parts  []string
+		part   strings.Builder
+		quoted bool
+	)
+
+	for _, r := range args {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			part.WriteRune(r)
+		case r == '|' && !quoted:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+
+	if quoted {
+		return nil, false
+	}
+	return append(parts, part.String()), true
+}
+
+// unquote strips the double quotes around the value
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// quote puts the value in double quotes if it contains "|", so that the
+// rule is shown the way it is added
+func quote(value string) string {
+	if strings.Contains(value, "|") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func formatRule(locale string, rule *entity.Rule) string {
+	match := string(rule.MatchType)
+	if rule.Pattern != "" {
+		match += ":" + quote(rule.Pattern)
+	}
+
+	response := string(rule.ResponseType)
+	if rule.Response != "" {
+		response += ":" + quote(rule.Response)
+	}
+
+	return i18n.T(locale, "rule.format", rule.ID, match, response, rule.Probability, rule.Cooldown)
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) handleSchedule(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleSchedule"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	locale := h.locale(ctx, update.Message)
+
+	if h.service.ScheduleService == nil {
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.disabled"))
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(commandArgs(update.Message.Text))
+
+	if len(args) == 0 {
+		h.listSchedules(ctx, update.Message, locale)
+		return
+	}
+
+	job := entity.JobKind(strings.ToLower(args[0]))
+
+	if len(args) == 2 && strings.EqualFold(args[1], "off") {
+		deleted, err := h.service.ScheduleService.Delete(ctx, chatID, job)
+		if err != nil {
+			logger.Error("Failed to delete schedule", zap.Error(err))
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.update_failed"))
+			return
+		}
+		if !deleted {
+			h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.not_scheduled", job))
+			return
+		}
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.off", job))
+		return
+	}
+
+	if len(args) < 2 {
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.usage"))
+		return
+	}
+
+	schedule := &entity.Schedule{
+		ChatID:    chatID,
+		Job:       job,
+		ThreadID:  int64(topicID(update.Message)),
+		Spec:      strings.Join(args[1:], " "),
+		CreatedBy: update.Message.From.ID,
+	}
+
+	if err := h.service.ScheduleService.Set(ctx, schedule); err != nil {
+		if errors.Is(err, usecase.ErrInvalidSchedule) {
+			h.sendMessage(ctx, update.Message, "❌ "+err.Error()+"\n\n"+i18n.T(locale, "schedule.usage"))
+			return
+		}
+		logger.Error("Failed to save schedule", zap.Error(err))
+		h.sendMessage(ctx, update.Message, i18n.T(locale, "schedule.save_failed"))
+		return
+	}
+
+	h.sendMessage(ctx, update.Message, "⏰ "+h.formatSchedule(locale, schedule))
+}
+
+func (h *Handler) listSchedules(ctx context.Context, msg *models.Message, locale string) {
+	const op = "bot/handler.listSchedules"
+
+	schedules, err := h.service.ScheduleService.List(ctx, msg.Chat.ID)
+	if err != nil {
+		h.logger.Error("Failed to list schedules", zap.String("op", op), zap.Error(err))
+		h.sendMessage(ctx, msg, i18n.T(locale, "schedule.list_failed"))
+		return
+	}
+
+	if len(schedules) == 0 {
+		h.sendMessage(ctx, msg, i18n.T(locale, "schedule.empty")+"\n\n"+i18n.T(locale, "schedule.usage"))
+		return
+	}
+
+	text := i18n.T(locale, "schedule.title") + "\n\n"
+	for _, schedule := range schedules {
+		text += h.formatSchedule(locale, schedule) + "\n"
+	}
+
+	h.sendMessage(ctx, msg, text)
+}
+
+func (h *Handler) formatSchedule(locale string, schedule *entity.Schedule) string {
+	text := fmt.Sprintf("%s (%s): %s", schedule.Job, i18n.T(locale, "job."+string(schedule.Job)), schedule.Spec)
+	return text + i18n.T(locale, "schedule.next", schedule.NextRunAt.In(h.location).Format("02.01 15:04 MST"))
+}
@@ -0,0 +1,74 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"go.uber.org/zap"
+)
+
+// toggle describes an on/off chat setting managed by a command
+type toggle struct {
+	command string
+	get     func(settings *entity.ChatSettings) bool
+	set     func(settings *entity.ChatSettings, enabled bool)
+	// state, on and off are catalog keys of the name of the setting shown
+	// with its current state and of the texts confirming a change
+	state string
+	on    string
+	off   string
+}
+
+// handleToggle shows the setting without arguments and lets admins change
+// it with "on" or "off"
+func (h *Handler) handleToggle(ctx context.Context, update *models.Update, t toggle) {
+	const op = "bot/handler.handleToggle"
+
+	logger := h.logger.With(zap.String("op", op), zap.String("command", t.command))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+
+	var enabled bool
+	switch commandArgs(update.Message.Text) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		settings, err := h.service.SettingsService.Get(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get chat settings", zap.Error(err))
+			h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.get_failed"))
+			return
+		}
+
+		state := h.t(ctx, update.Message, "settings.disabled")
+		if t.get(settings) {
+			state = h.t(ctx, update.Message, "settings.enabled")
+		}
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.state", h.t(ctx, update.Message, t.state), state)+"\n\n"+
+			h.t(ctx, update.Message, "settings.usage", t.command))
+		return
+	}
+
+	err := h.service.SettingsService.Update(ctx, chatID, func(settings *entity.ChatSettings) {
+		t.set(settings, enabled)
+	})
+	if err != nil {
+		logger.Error("Failed to update chat settings", zap.Error(err))
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.save_failed"))
+		return
+	}
+
+	if enabled {
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, t.on))
+		return
+	}
+	h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, t.off))
+}
@@ -18,15 +18,9 @@ func (h *Handler) handleStart(ctx context.Context, b *bot.Bot, update *models.Up
 		return
 	}
 
-	msg := "👋 Привет, я Egonez!\n\n"
-	msg += "Я могу учиться на ваших сообщениях и генерировать ответы. Вот что я могу делать:\n"
-	msg += "- /gen чтобы сгенерировать ответ\n"
-	msg += "- /clear чтобы очистить историю чата\n"
-	msg += "- /sticker чтобы получить случайную стикеровую эмодзи\n"
-	msg += "- /stats чтобы посмотреть статистику чата"
-
 	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
-		Text:   msg,
+		ChatID:          update.Message.Chat.ID,
+		MessageThreadID: topicID(update.Message),
+		Text:            h.startText(h.locale(ctx, update.Message)),
 	})
 }
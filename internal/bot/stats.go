@@ -3,12 +3,19 @@ package bot
 import (
 	"context"
 	"fmt"
+	"html"
+	"strings"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
 	"go.uber.org/zap"
 )
 
+// heatmapLevels draw the hourly activity from idle to the busiest hour
+var heatmapLevels = []rune(" ▁▂▃▄▅▆▇█")
+
 func (h *Handler) handleStats(ctx context.Context, b *bot.Bot, update *models.Update) {
 	const op = "bot/handler.handleStats"
 
@@ -19,17 +26,176 @@ func (h *Handler) handleStats(ctx context.Context, b *bot.Bot, update *models.Up
 		return
 	}
 
+	period, ok := parseStatsPeriod(commandArgs(update.Message.Text))
+	if !ok {
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "stats.usage"))
+		return
+	}
+
 	chatID := update.Message.Chat.ID
-	stats, err := h.service.BotService.GetChatStats(ctx, chatID)
+	stats, err := h.service.BotService.GetChatStats(ctx, chatID, period)
 	if err != nil {
-		h.sendMessage(ctx, chatID, "❌ Не удалось получить статистику чата. Попробуйте позже.")
+		logger.Error("Failed to get chat stats", zap.Error(err))
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "stats.failed"))
 		return
 	}
 
-	msg := fmt.Sprintf("📊 Статистика чата\n\n"+
-		"Сообщений: %d\n"+
-		"Стикеров: %d",
-		stats.MessageCount, stats.StickerCount)
+	locale := h.locale(ctx, update.Message)
+	h.resolveNames(ctx, chatID, locale, stats.TopUsers)
+	h.sendHTML(ctx, update.Message, formatStats(locale, stats))
+}
+
+func parseStatsPeriod(arg string) (entity.StatsPeriod, bool) {
+	switch strings.ToLower(arg) {
+	case "", "all", "всё", "все":
+		return entity.StatsPeriodAll, true
+	case "week", "неделя":
+		return entity.StatsPeriodWeek, true
+	case "month", "месяц":
+		return entity.StatsPeriodMonth, true
+	default:
+		return "", false
+	}
+}
+
+// periodTitle returns the name of the period stats are shown for
+func periodTitle(locale string, period entity.StatsPeriod) string {
+	return i18n.T(locale, "period."+string(period))
+}
+
+func formatStats(locale string, stats *entity.ChatStats) string {
+	var sb strings.Builder
+
+	sb.WriteString(i18n.T(locale, "stats.title", periodTitle(locale, stats.Period)) + "\n\n")
+	sb.WriteString(i18n.T(locale, "stats.messages", stats.MessageCount) + "\n")
+	sb.WriteString(i18n.T(locale, "stats.stickers", stats.StickerCount) + "\n")
+	sb.WriteString(i18n.T(locale, "stats.vocabulary",
+		i18n.N(locale, "stats.words", stats.Vocabulary, stats.Vocabulary),
+		i18n.N(locale, "stats.states", stats.States, stats.States),
+	) + "\n")
+
+	if len(stats.TopWords) > 0 {
+		sb.WriteString("\n" + i18n.T(locale, "stats.top_words") + "\n")
+		for i, word := range stats.TopWords {
+			fmt.Fprintf(&sb, "%d. %s — %d\n", i+1, html.EscapeString(word.Word), word.Count)
+		}
+	}
+
+	if len(stats.TopUsers) > 0 {
+		sb.WriteString("\n" + i18n.T(locale, "stats.top_users") + "\n")
+		for i, user := range stats.TopUsers {
+			fmt.Fprintf(&sb, "%d. %s — %d\n", i+1, userLink(user), user.Count)
+		}
+	}
+
+	if len(stats.Daily) > 0 {
+		busiest := stats.Daily[0]
+		total := 0
+		for _, day := range stats.Daily {
+			total += day.Count
+			if day.Count > busiest.Count {
+				busiest = day
+			}
+		}
+
+		average := total / len(stats.Daily)
+		sb.WriteString("\n" + i18n.T(locale, "stats.daily") + "\n")
+		sb.WriteString(i18n.N(locale, "stats.daily_average", average,
+			average, busiest.Day.Format("02.01.2006"), busiest.Count) + "\n")
+	}
+
+	if len(stats.Hourly) > 0 {
+		sb.WriteString("\n" + i18n.T(locale, "stats.hourly") + "\n")
+		sb.WriteString("<pre>" + heatmap(stats.Hourly) + "</pre>\n")
+	}
+
+	if len(stats.StickerSets) > 0 {
+		sb.WriteString("\n" + i18n.T(locale, "stats.sticker_sets") + "\n")
+		for i, set := range stats.StickerSets {
+			fmt.Fprintf(&sb, "%d. <a href=\"https://t.me/addstickers/%s\">%s</a> — %d\n",
+				i+1, html.EscapeString(set.SetName), html.EscapeString(set.SetName), set.Count)
+		}
+	}
 
-	h.sendMessage(ctx, chatID, msg)
+	return sb.String()
+}
+
+// heatmap draws the activity of every hour of the day as a bar
+func heatmap(hours []entity.HourCount) string {
+	var counts [24]int
+	busiest := 0
+	for _, hour := range hours {
+		if hour.Hour < 0 || hour.Hour >= len(counts) {
+			continue
+		}
+		counts[hour.Hour] = hour.Count
+		busiest = max(busiest, hour.Count)
+	}
+
+	bars := make([]rune, len(counts))
+	for i, count := range counts {
+		level := 0
+		if busiest > 0 {
+			level = count * (len(heatmapLevels) - 1) / busiest
+		}
+		bars[i] = heatmapLevels[level]
+	}
+
+	return string(bars) + "\n0     6     12    18   23"
+}
+
+// resolveNames names the users as they are known in the chat. Channel posts
+// and anonymous admins are sent on behalf of a chat and named after it
+func (h *Handler) resolveNames(ctx context.Context, chatID int64, locale string, users []entity.UserCount) {
+	for i := range users {
+		users[i].Name = h.userName(ctx, chatID, locale, users[i].UserID)
+	}
+}
+
+func (h *Handler) userName(ctx context.Context, chatID int64, locale string, userID int64) string {
+	if userID <= 0 {
+		return i18n.T(locale, "stats.channel")
+	}
+
+	member, err := h.bot.GetChatMember(ctx, &bot.GetChatMemberParams{
+		ChatID: chatID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Sprint(userID)
+	}
+
+	user := memberUser(member)
+	if user == nil {
+		return fmt.Sprint(userID)
+	}
+
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// userLink returns an HTML link to the user
+func userLink(user entity.UserCount) string {
+	if user.UserID <= 0 {
+		return "👤 " + html.EscapeString(user.Name)
+	}
+	return fmt.Sprintf("<a href=\"tg://user?id=%d\">%s</a>", user.UserID, html.EscapeString(user.Name))
+}
+
+func memberUser(member *models.ChatMember) *models.User {
+	switch {
+	case member.Owner != nil:
+		return member.Owner.User
+	case member.Administrator != nil:
+		return &member.Administrator.User
+	case member.Member != nil:
+		return member.Member.User
+	case member.Restricted != nil:
+		return member.Restricted.User
+	case member.Left != nil:
+		return member.Left.User
+	case member.Banned != nil:
+		return member.Banned.User
+	default:
+		return nil
+	}
 }
@@ -2,9 +2,12 @@ package bot
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
 	"go.uber.org/zap"
 )
 
@@ -19,18 +22,55 @@ func (h *Handler) handleSticker(ctx context.Context, b *bot.Bot, update *models.
 	}
 
 	chatID := update.Message.Chat.ID
-	sticker, err := h.service.BotService.GetRandomSticker(ctx, chatID)
+	emoji := commandArgs(update.Message.Text)
+
+	sticker, err := h.service.BotService.GetRandomSticker(ctx, chatID, emoji)
 	if err != nil {
-		h.sendMessage(ctx, chatID, "❌ Не удалось получить стикер. Отправьте мне несколько стикеров!")
+		if emoji != "" && errors.Is(err, sql.ErrNoRows) {
+			h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "sticker.unknown_emoji", emoji))
+			return
+		}
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "sticker.failed"))
 		return
 	}
 
-	h.bot.SendSticker(ctx, &bot.SendStickerParams{
-		ChatID: chatID,
-		Sticker: &models.InputFileString{
-			Data: sticker.FileID,
-		},
-	})
+	h.sendSticker(ctx, update.Message, sticker.FileID, false)
+}
+
+// sendSticker sends the sticker to the chat and forum topic the message came
+// from, quoting the message if reply is set
+func (h *Handler) sendSticker(ctx context.Context, to *models.Message, fileID string, reply bool) {
+	params := &bot.SendStickerParams{
+		ChatID:          to.Chat.ID,
+		MessageThreadID: topicID(to),
+		Sticker:         &models.InputFileString{Data: fileID},
+	}
+	if reply {
+		params.ReplyParameters = replyTo(to)
+	}
+
+	h.bot.SendSticker(ctx, params)
+	h.limiter.markSent(to.Chat.ID)
+}
+
+// replySticker answers the message with a sticker fitting its text and
+// reports whether one was found
+func (h *Handler) replySticker(ctx context.Context, msg *models.Message, text string) bool {
+	const op = "bot/handler.replySticker"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	sticker, err := h.service.BotService.PickSticker(ctx, msg.Chat.ID, text)
+	if err != nil {
+		logger.Error("Failed to pick sticker", zap.Error(err))
+		return false
+	}
+	if sticker == nil {
+		return false
+	}
+
+	h.sendSticker(ctx, msg, sticker.FileID, true)
+	return true
 }
 
 func (h *Handler) handleStickerMessage(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -39,20 +79,35 @@ func (h *Handler) handleStickerMessage(ctx context.Context, b *bot.Bot, update *
 	logger := h.logger.With(zap.String("op", op))
 
 	if update.Message == nil || update.Message.Sticker == nil {
-		logger.Error("update.Message is nil or update.Message.Sticker is nil")
+		logger.Error("update.Message or update.Message.Sticker is nil")
 		return
 	}
 
-	chatID := update.Message.Chat.ID
-	userID := update.Message.From.ID
 	sticker := update.Message.Sticker
 
-	err := h.service.BotService.(interface {
-		HandleSticker(ctx context.Context, chatID, userID int64, fileID, setName string) error
-	}).HandleSticker(ctx, chatID, userID, sticker.FileID, sticker.SetName)
-
+	err := h.service.BotService.HandleSticker(ctx, &entity.Sticker{
+		ChatID:       update.Message.Chat.ID,
+		FileID:       sticker.FileID,
+		FileUniqueID: sticker.FileUniqueID,
+		SetName:      sticker.SetName,
+		Emoji:        sticker.Emoji,
+		Type:         stickerType(sticker),
+	})
 	if err != nil {
-
 		logger.Error("Failed to handle sticker", zap.Error(err))
+		return
+	}
+
+	h.learnMedia(ctx, update.Message, entity.MediaSticker, sticker.FileUniqueID)
+}
+
+func stickerType(sticker *models.Sticker) entity.StickerType {
+	switch {
+	case sticker.IsVideo:
+		return entity.StickerTypeVideo
+	case sticker.IsAnimated:
+		return entity.StickerTypeAnimated
+	default:
+		return entity.StickerTypeStatic
 	}
 }
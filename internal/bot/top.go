@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/i18n"
+	"go.uber.org/zap"
+)
+
+const (
+	// topLimit is the number of messages /top lists
+	topLimit = 10
+	// topTextLength bounds the characters shown of every message
+	topTextLength = 100
+)
+
+func (h *Handler) handleTop(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleTop"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil {
+		logger.Error("update.Message is nil")
+		return
+	}
+
+	msg := update.Message
+	locale := h.locale(ctx, msg)
+
+	top, err := h.service.FeedbackService.Top(ctx, msg.Chat.ID, topLimit)
+	if err != nil {
+		logger.Error("Failed to get top generations", zap.Error(err))
+		h.sendMessage(ctx, msg, i18n.T(locale, "top.failed"))
+		return
+	}
+	if len(top) == 0 {
+		h.sendMessage(ctx, msg, i18n.T(locale, "top.empty"))
+		return
+	}
+
+	h.sendHTML(ctx, msg, formatTop(locale, msg.Chat, top))
+}
+
+// formatTop lists the generations with their likes, linking the messages
+// where the chat allows it
+func formatTop(locale string, chat models.Chat, top []*entity.Generation) string {
+	var sb strings.Builder
+
+	sb.WriteString(i18n.T(locale, "top.title") + "\n\n")
+	for i, generation := range top {
+		text := html.EscapeString(shorten(generation.Text, topTextLength))
+		if link := messageLink(chat, generation.MessageID); link != "" {
+			text = fmt.Sprintf("<a href=\"%s\">%s</a>", link, text)
+		}
+		fmt.Fprintf(&sb, "%d. %s — %s\n", i+1, text, counted("👍", generation.Likes))
+	}
+
+	return sb.String()
+}
+
+// shorten cuts the text to at most n characters
+func shorten(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// messageLink returns the link to a message of a public chat or of a
+// supergroup, empty for other chats
+func messageLink(chat models.Chat, messageID int64) string {
+	const supergroupPrefix = -1000000000000
+
+	switch {
+	case chat.Username != "":
+		return fmt.Sprintf("https://t.me/%s/%d", chat.Username, messageID)
+	case chat.ID < supergroupPrefix:
+		return fmt.Sprintf("https://t.me/c/%d/%d", supergroupPrefix-chat.ID, messageID)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,22 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+)
+
+var topicsToggle = toggle{
+	command: "topics",
+	get:     func(settings *entity.ChatSettings) bool { return settings.TopicChains },
+	set:     func(settings *entity.ChatSettings, enabled bool) { settings.TopicChains = enabled },
+	state:   "topics.state",
+	on:      "topics.on",
+	off:     "topics.off",
+}
+
+func (h *Handler) handleTopics(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.handleToggle(ctx, update, topicsToggle)
+}
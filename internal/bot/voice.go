@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+	"go.uber.org/zap"
+)
+
+var _ ports.FileDownloader = (*Handler)(nil)
+
+// Download fetches a file by its ID via getFile
+func (h *Handler) Download(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	file, err := h.bot.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.bot.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download file: unexpected status %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// handleVoiceMessage queues voice messages and video notes for transcription
+func (h *Handler) handleVoiceMessage(ctx context.Context, msg *models.Message) {
+	const op = "bot/handler.handleVoiceMessage"
+
+	if h.service.TranscriptionService == nil || !h.shouldLearn(msg) {
+		return
+	}
+
+	var fileID string
+	var duration int
+	switch {
+	case msg.Voice != nil:
+		fileID, duration = msg.Voice.FileID, msg.Voice.Duration
+	case msg.VideoNote != nil:
+		fileID, duration = msg.VideoNote.FileID, msg.VideoNote.Duration
+	default:
+		return
+	}
+
+	if time.Duration(duration)*time.Second > h.maxVoiceDuration {
+		return
+	}
+
+	job := adapters.VoiceJob{
+		Message:    newMessage(msg, ""),
+		FileID:     fileID,
+		Downloader: h,
+	}
+
+	if !h.service.TranscriptionService.Enqueue(job) {
+		h.logger.Warn("Transcription queue is full", zap.String("op", op), zap.Int64("chat_id", msg.Chat.ID))
+	}
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// maxWakeWords bounds the number of wake words per chat
+const maxWakeWords = 20
+
+func (h *Handler) handleWake(ctx context.Context, b *bot.Bot, update *models.Update) {
+	const op = "bot/handler.handleWake"
+
+	logger := h.logger.With(zap.String("op", op))
+
+	if update.Message == nil || update.Message.From == nil {
+		logger.Error("update.Message or update.Message.From is nil")
+		return
+	}
+
+	chatID := update.Message.Chat.ID
+	args := commandArgs(update.Message.Text)
+
+	if args == "" {
+		settings, err := h.service.SettingsService.Get(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get chat settings", zap.Error(err))
+			h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.get_failed"))
+			return
+		}
+
+		if len(settings.WakeWords) == 0 {
+			h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "wake.empty"))
+			return
+		}
+
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "wake.words", strings.Join(settings.WakeWords, ", ")))
+		return
+	}
+
+	var words []string
+	if args != "clear" {
+		words = usecase.SplitWords(strings.ToLower(args))
+		if len(words) > maxWakeWords {
+			words = words[:maxWakeWords]
+		}
+	}
+
+	err := h.service.SettingsService.Update(ctx, chatID, func(settings *entity.ChatSettings) {
+		settings.WakeWords = words
+	})
+	if err != nil {
+		logger.Error("Failed to update chat settings", zap.Error(err))
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "settings.save_failed"))
+		return
+	}
+
+	if len(words) == 0 {
+		h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "wake.cleared"))
+		return
+	}
+
+	h.sendMessage(ctx, update.Message, h.t(ctx, update.Message, "wake.words", strings.Join(words, ", ")))
+}
+
+// addressedSeed reports whether the message is addressed to the bot, by
+// mentioning it, replying to it or containing a wake word, and returns the
+// text to seed the answer with
+func (h *Handler) addressedSeed(msg *models.Message, text string, wakeWords []string) (string, bool) {
+	if msg.ReplyToMessage != nil && msg.ReplyToMessage.From != nil && msg.ReplyToMessage.From.ID == h.me.ID {
+		return text, true
+	}
+
+	if h.mention.MatchString(text) {
+		return strings.TrimSpace(h.mention.ReplaceAllString(text, "")), true
+	}
+
+	if len(wakeWords) == 0 {
+		return "", false
+	}
+
+	for _, word := range usecase.SplitWords(strings.ToLower(text)) {
+		for _, wake := range wakeWords {
+			if word == wake {
+				return text, true
+			}
+		}
+	}
+
+	return "", false
+}
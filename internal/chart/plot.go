@@ -0,0 +1,146 @@
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+var _ ports.ChartRenderer = (*Plot)(nil)
+
+const (
+	width  = 8 * vg.Inch
+	height = 9 * vg.Inch
+	dpi    = 96
+)
+
+var (
+	lineColor = color.RGBA{R: 0x2a, G: 0x9d, B: 0xf4, A: 0xff}
+	barColor  = color.RGBA{R: 0xf4, G: 0x8c, B: 0x2a, A: 0xff}
+)
+
+// Plot renders charts with gonum/plot, entirely in memory
+type Plot struct{}
+
+func NewPlot() *Plot {
+	return &Plot{}
+}
+
+// Render draws the daily activity above the most active users as a PNG
+func (p *Plot) Render(stats *entity.ChatStats, labels entity.ChartLabels) ([]byte, error) {
+	if len(stats.Daily) == 0 {
+		return nil, ports.ErrNoChartData
+	}
+
+	activity, err := activityPlot(stats.Daily, labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plot activity: %w", err)
+	}
+
+	users, err := usersPlot(stats.TopUsers, labels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plot users: %w", err)
+	}
+
+	img := vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(dpi), vgimg.UseBackgroundColor(color.White))
+	dc := draw.New(img)
+
+	tiles := draw.Tiles{
+		Rows:      2,
+		Cols:      1,
+		PadX:      vg.Millimeter * 4,
+		PadY:      vg.Millimeter * 8,
+		PadTop:    vg.Millimeter * 4,
+		PadBottom: vg.Millimeter * 4,
+		PadLeft:   vg.Millimeter * 4,
+		PadRight:  vg.Millimeter * 4,
+	}
+
+	plots := [][]*plot.Plot{{activity}, {users}}
+	canvases := plot.Align(plots, tiles, dc)
+	for i := range plots {
+		plots[i][0].Draw(canvases[i][0])
+	}
+
+	var buf bytes.Buffer
+	if _, err := (vgimg.PngCanvas{Canvas: img}).WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func activityPlot(days []entity.DayCount, labels entity.ChartLabels) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = labels.Daily
+	p.Y.Label.Text = labels.Messages
+	p.Y.Min = 0
+	p.X.Tick.Marker = plot.TimeTicks{Format: "02.01"}
+	p.Add(plotter.NewGrid())
+
+	points := make(plotter.XYs, len(days))
+	for i, day := range days {
+		points[i].X = float64(day.Day.Unix())
+		points[i].Y = float64(day.Count)
+	}
+
+	// A single day would collapse the axis into a point
+	if len(points) == 1 {
+		points = append(plotter.XYs{{X: float64(days[0].Day.Add(-24 * time.Hour).Unix())}}, points...)
+	}
+
+	line, scatter, err := plotter.NewLinePoints(points)
+	if err != nil {
+		return nil, err
+	}
+	line.Color = lineColor
+	line.Width = vg.Points(2)
+	scatter.Color = lineColor
+
+	p.Add(line, scatter)
+	return p, nil
+}
+
+func usersPlot(users []entity.UserCount, labels entity.ChartLabels) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = labels.Users
+	p.X.Label.Text = labels.Messages
+	p.X.Min = 0
+
+	if len(users) == 0 {
+		return p, nil
+	}
+
+	// Bars are drawn bottom up, put the most active user on top
+	values := make(plotter.Values, len(users))
+	names := make([]string, len(users))
+	for i, user := range users {
+		j := len(users) - 1 - i
+		values[j] = float64(user.Count)
+		names[j] = user.Name
+		if names[j] == "" {
+			names[j] = fmt.Sprint(user.UserID)
+		}
+	}
+
+	bars, err := plotter.NewBarChart(values, vg.Points(24))
+	if err != nil {
+		return nil, err
+	}
+	bars.Horizontal = true
+	bars.Color = barColor
+	bars.LineStyle.Width = 0
+
+	p.Add(bars)
+	p.NominalY(names...)
+	return p, nil
+}
@@ -0,0 +1,80 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"gonum.org/v1/plot/vg"
+)
+
+var testLabels = entity.ChartLabels{Daily: "Messages per day", Users: "Most active", Messages: "Messages"}
+
+func sampleStats() *entity.ChatStats {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	stats := &entity.ChatStats{
+		TopUsers: []entity.UserCount{
+			{UserID: 1, Count: 120, Name: "Alice"},
+			{UserID: 2, Count: 80, Name: "Боб"},
+			{UserID: 3, Count: 15},
+		},
+	}
+	for i := range 7 {
+		stats.Daily = append(stats.Daily, entity.DayCount{
+			Day:   start.AddDate(0, 0, i),
+			Count: 10 + i*5,
+		})
+	}
+	return stats
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats func() *entity.ChatStats
+	}{
+		{name: "week", stats: sampleStats},
+		{name: "single day", stats: func() *entity.ChatStats {
+			stats := sampleStats()
+			stats.Daily = stats.Daily[:1]
+			return stats
+		}},
+		{name: "no users", stats: func() *entity.ChatStats {
+			stats := sampleStats()
+			stats.TopUsers = nil
+			return stats
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := NewPlot().Render(tt.stats(), testLabels)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decode png: %v", err)
+			}
+
+			bounds := img.Bounds()
+			wantW, wantH := int(width/vg.Inch*dpi), int(height/vg.Inch*dpi)
+			if bounds.Dx() != wantW || bounds.Dy() != wantH {
+				t.Errorf("image is %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), wantW, wantH)
+			}
+		})
+	}
+}
+
+func TestRenderNoData(t *testing.T) {
+	_, err := NewPlot().Render(&entity.ChatStats{}, testLabels)
+	if !errors.Is(err, ports.ErrNoChartData) {
+		t.Errorf("Render error = %v, want %v", err, ports.ErrNoChartData)
+	}
+}
@@ -1,6 +1,49 @@
 package entity
 
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
 type ChatStats struct {
+	Period       StatsPeriod
 	MessageCount int
 	StickerCount int
+
+	TopWords    []WordCount
+	TopUsers    []UserCount
+	Daily       []DayCount
+	Hourly      []HourCount
+	StickerSets []StickerSetCount
+
+	// Vocabulary and States describe the chat's Markov chain
+	Vocabulary int
+	States     int
+}
+
+type ChatSettings struct {
+	bun.BaseModel `bun:"table:chat_settings,alias:cs"`
+
+	ChatID    int64    `bun:"chat_id,pk" json:"chat_id"`
+	WakeWords []string `bun:"wake_words,array" json:"wake_words"`
+	// TopicChains keeps a separate chain for every forum topic
+	TopicChains bool `bun:"topic_chains,notnull,default:false" json:"topic_chains"`
+	// LearnForwards trains on forwarded messages, they are skipped otherwise
+	LearnForwards bool `bun:"learn_forwards,notnull,default:false" json:"learn_forwards"`
+	// RetentionDays deletes messages older than that many days, zero keeps
+	// them forever
+	RetentionDays int `bun:"retention_days,notnull,default:0" json:"retention_days"`
+	// RetentionMessages keeps at most that many latest messages, zero keeps
+	// all of them
+	RetentionMessages int `bun:"retention_messages,notnull,default:0" json:"retention_messages"`
+	// Language the bot speaks and generates in, detected from the messages
+	// if empty
+	Language  string    `bun:"language,notnull,default:''" json:"language"`
+	UpdatedAt time.Time `bun:"updated_at,notnull,default:now()" json:"updated_at"`
+}
+
+// HasRetention reports whether the chat limits how long messages are kept
+func (s *ChatSettings) HasRetention() bool {
+	return s.RetentionDays > 0 || s.RetentionMessages > 0
 }
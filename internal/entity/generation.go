@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Generation is a generated message sent by the bot, kept so that users can
+// rate it and their ratings are fed back into the chain it came from
+type Generation struct {
+	bun.BaseModel `bun:"table:generations,alias:g"`
+
+	ID     int64 `bun:"id,pk,autoincrement" json:"id"`
+	ChatID int64 `bun:"chat_id,notnull" json:"chat_id"`
+	// ThreadID is the forum topic the message was sent to, ChainThreadID
+	// the thread of the chain it was generated by
+	ThreadID      int64 `bun:"thread_id,notnull,default:0" json:"thread_id"`
+	ChainThreadID int64 `bun:"chain_thread_id,notnull,default:0" json:"chain_thread_id"`
+	MessageID     int64 `bun:"message_id,notnull" json:"message_id"`
+	// RequestedBy is the user the message answers, zero for messages the
+	// bot sent on its own
+	RequestedBy int64  `bun:"requested_by,notnull,default:0" json:"requested_by"`
+	Seed        string `bun:"seed,notnull,default:''" json:"seed"`
+	Text        string `bun:"text,notnull" json:"text"`
+	Likes       int    `bun:"likes,notnull,default:0" json:"likes"`
+	Dislikes    int    `bun:"dislikes,notnull,default:0" json:"dislikes"`
+	// Deleted is set once the message is deleted, its rating is still used
+	Deleted   bool      `bun:"deleted,notnull,default:false" json:"deleted"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// Score is the balance of likes and dislikes
+func (g *Generation) Score() int {
+	return g.Likes - g.Dislikes
+}
+
+// VoteChange returns how the likes and dislikes of a generation change when
+// a vote goes from before to after, zero standing for no vote
+func VoteChange(before, after int) (likes, dislikes int) {
+	return count(after > 0) - count(before > 0), count(after < 0) - count(before < 0)
+}
+
+func count(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+// VoteSource is the way a vote was given
+type VoteSource string
+
+const (
+	VoteButton   VoteSource = "button"
+	VoteReaction VoteSource = "reaction"
+)
+
+// Vote is the rating a user gave a generation, 1 for a like and -1 for a
+// dislike. A user has a single vote per generation whatever the source.
+type Vote struct {
+	bun.BaseModel `bun:"table:generation_votes,alias:gv"`
+
+	GenerationID int64      `bun:"generation_id,pk" json:"generation_id"`
+	UserID       int64      `bun:"user_id,pk" json:"user_id"`
+	Value        int        `bun:"value,notnull" json:"value"`
+	Source       VoteSource `bun:"source,notnull,default:'button'" json:"source"`
+	CreatedAt    time.Time  `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// MediaKind is a kind of media the bot learns as a token of the chat
+// vocabulary
+type MediaKind string
+
+const (
+	MediaSticker   MediaKind = "sticker"
+	MediaAnimation MediaKind = "animation"
+)
+
+// MediaToken returns the token standing for the media in the message
+// stream, e.g. "<sticker:AgADBQADyA4AAg>"
+func MediaToken(kind MediaKind, fileUniqueID string) string {
+	return "<" + string(kind) + ":" + fileUniqueID + ">"
+}
+
+// ParseMediaToken reports whether the token stands for media and returns
+// its kind and file_unique_id
+func ParseMediaToken(token string) (MediaKind, string, bool) {
+	if !strings.HasPrefix(token, "<") || !strings.HasSuffix(token, ">") {
+		return "", "", false
+	}
+
+	kind, id, ok := strings.Cut(token[1:len(token)-1], ":")
+	if !ok || id == "" {
+		return "", "", false
+	}
+
+	switch MediaKind(kind) {
+	case MediaSticker, MediaAnimation:
+		return MediaKind(kind), id, true
+	default:
+		return "", "", false
+	}
+}
+
+// IsMediaToken reports whether the token stands for media
+func IsMediaToken(token string) bool {
+	_, _, ok := ParseMediaToken(token)
+	return ok
+}
+
+// Animation is a GIF seen in a chat, stored once per chat like stickers
+type Animation struct {
+	bun.BaseModel `bun:"table:chat_animations,alias:a"`
+
+	ChatID       int64     `bun:"chat_id,pk" json:"chat_id"`
+	FileUniqueID string    `bun:"file_unique_id,pk" json:"file_unique_id"`
+	FileID       string    `bun:"file_id,notnull" json:"file_id"`
+	UsageCount   int64     `bun:"usage_count,notnull,default:1" json:"usage_count"`
+	CreatedAt    time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	LastSeenAt   time.Time `bun:"last_seen_at,notnull,default:now()" json:"last_seen_at"`
+}
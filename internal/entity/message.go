@@ -6,12 +6,37 @@ import (
 	"github.com/uptrace/bun"
 )
 
+type MessageSource string
+
+const (
+	MessageSourceText      MessageSource = "text"
+	MessageSourceVoice     MessageSource = "voice"
+	MessageSourceSticker   MessageSource = "sticker"
+	MessageSourceAnimation MessageSource = "animation"
+)
+
+// IsMedia reports whether the message text is a media token
+func (s MessageSource) IsMedia() bool {
+	return s == MessageSourceSticker || s == MessageSourceAnimation
+}
+
+// UserChat is a chat a user writes to, ThreadID is the forum topic the user
+// wrote to last, zero outside of topics
+type UserChat struct {
+	ChatID   int64 `bun:"chat_id"`
+	ThreadID int64 `bun:"thread_id"`
+}
+
 type Message struct {
 	bun.BaseModel `bun:"table:messages,alias:m"`
 
-	ID        int64     `bun:"id,pk,autoincrement" json:"id"`
-	ChatID    int64     `bun:"chat_id,notnull" json:"chat_id"`
-	UserID    int64     `bun:"user_id,notnull" json:"user_id"`
-	Text      string    `bun:"text,notnull" json:"text"`
-	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	ID        int64         `bun:"id,pk,autoincrement" json:"id"`
+	ChatID    int64         `bun:"chat_id,notnull" json:"chat_id"`
+	MessageID int64         `bun:"message_id,notnull,default:0" json:"message_id"`
+	ThreadID  int64         `bun:"thread_id,notnull,default:0" json:"thread_id"`
+	UserID    int64         `bun:"user_id,notnull" json:"user_id"`
+	Text      string        `bun:"text,notnull" json:"text"`
+	Forwarded bool          `bun:"forwarded,notnull,default:false" json:"forwarded"`
+	Source    MessageSource `bun:"source,notnull,default:'text'" json:"source"`
+	CreatedAt time.Time     `bun:"created_at,notnull,default:now()" json:"created_at"`
 }
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Quote is a message saved to the quote book of the chat
+type Quote struct {
+	bun.BaseModel `bun:"table:quotes,alias:q"`
+
+	ID        int64 `bun:"id,pk,autoincrement" json:"id"`
+	ChatID    int64 `bun:"chat_id,notnull" json:"chat_id"`
+	ThreadID  int64 `bun:"thread_id,notnull,default:0" json:"thread_id"`
+	MessageID int64 `bun:"message_id,notnull" json:"message_id"`
+	// UserID is the author, or the chat a channel post or an anonymous
+	// admin message was sent on behalf of
+	UserID int64 `bun:"user_id,notnull" json:"user_id"`
+	// Author is the name of the author when the quote was saved, empty if
+	// it isn't known
+	Author  string `bun:"author,notnull,default:''" json:"author"`
+	Text    string `bun:"text,notnull" json:"text"`
+	SavedBy int64  `bun:"saved_by,notnull,default:0" json:"saved_by"`
+	// SentAt is the time the message was sent, CreatedAt the time it was
+	// saved
+	SentAt    time.Time `bun:"sent_at,notnull" json:"sent_at"`
+	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// GlobalChatID is the chat ID of rules applied to every chat
+const GlobalChatID int64 = 0
+
+type RuleMatchType string
+
+const (
+	RuleMatchRegex   RuleMatchType = "regex"
+	RuleMatchWord    RuleMatchType = "word"
+	RuleMatchVoice   RuleMatchType = "voice"
+	RuleMatchVideo   RuleMatchType = "video"
+	RuleMatchSticker RuleMatchType = "sticker"
+	RuleMatchBoost   RuleMatchType = "boost"
+)
+
+type RuleResponseType string
+
+const (
+	RuleResponseText     RuleResponseType = "text"
+	RuleResponseSticker  RuleResponseType = "sticker"
+	RuleResponseGenerate RuleResponseType = "generate"
+	RuleResponseReaction RuleResponseType = "reaction"
+)
+
+type Rule struct {
+	bun.BaseModel `bun:"table:rules,alias:r"`
+
+	ID           int64            `bun:"id,pk,autoincrement" json:"id"`
+	ChatID       int64            `bun:"chat_id,notnull" json:"chat_id"`
+	MatchType    RuleMatchType    `bun:"match_type,notnull" json:"match_type"`
+	Pattern      string           `bun:"pattern,notnull" json:"pattern"`
+	ResponseType RuleResponseType `bun:"response_type,notnull" json:"response_type"`
+	Response     string           `bun:"response,notnull" json:"response"`
+	Probability  float64          `bun:"probability,notnull,default:1" json:"probability"`
+	Cooldown     time.Duration    `bun:"cooldown,notnull,default:0" json:"cooldown"`
+	CreatedBy    int64            `bun:"created_by,notnull,default:0" json:"created_by"`
+	CreatedAt    time.Time        `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
+
+// RuleMatch is a rule that fired together with the text fragment it matched
+type RuleMatch struct {
+	Rule *Rule
+	Seed string
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+type JobKind string
+
+const (
+	// JobQuote posts a generated "quote of the day"
+	JobQuote JobKind = "quote"
+	// JobDigest posts the chat statistics of the past week
+	JobDigest JobKind = "digest"
+	// JobRetrain rebuilds the chains of the chat from the stored messages
+	JobRetrain JobKind = "retrain"
+	// JobRetention enforces the retention policy of the chat, see
+	// ChatSettings.HasRetention
+	JobRetention JobKind = "retention"
+)
+
+// Schedule is a job the scheduler runs for a chat
+type Schedule struct {
+	bun.BaseModel `bun:"table:schedules,alias:sch"`
+
+	ID     int64   `bun:"id,pk,autoincrement" json:"id"`
+	ChatID int64   `bun:"chat_id,notnull,unique:schedules_chat_id_job" json:"chat_id"`
+	Job    JobKind `bun:"job,notnull,unique:schedules_chat_id_job" json:"job"`
+	// ThreadID is the forum topic posts go to, zero outside of topics
+	ThreadID int64 `bun:"thread_id,notnull,default:0" json:"thread_id"`
+	// Spec is when the job runs, see scheduler.ParseSpec
+	Spec string `bun:"spec,notnull" json:"spec"`
+	// Arg is an optional job argument
+	Arg       string       `bun:"arg,notnull,default:''" json:"arg"`
+	NextRunAt time.Time    `bun:"next_run_at,notnull" json:"next_run_at"`
+	LastRunAt bun.NullTime `bun:"last_run_at" json:"last_run_at"`
+	CreatedBy int64        `bun:"created_by,notnull,default:0" json:"created_by"`
+	CreatedAt time.Time    `bun:"created_at,notnull,default:now()" json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package entity
+
+import "time"
+
+// StatsPeriod is the time window chat statistics are collected over
+type StatsPeriod string
+
+const (
+	StatsPeriodWeek  StatsPeriod = "week"
+	StatsPeriodMonth StatsPeriod = "month"
+	StatsPeriodAll   StatsPeriod = "all"
+)
+
+// Since returns the start of the period, the zero time for all time
+func (p StatsPeriod) Since(now time.Time) time.Time {
+	switch p {
+	case StatsPeriodWeek:
+		return now.AddDate(0, 0, -7)
+	case StatsPeriodMonth:
+		return now.AddDate(0, -1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+type WordCount struct {
+	Word  string `bun:"word"`
+	Count int    `bun:"count"`
+}
+
+// ChartLabels are the texts a chart of the statistics is drawn with
+type ChartLabels struct {
+	// Daily and Users title the daily activity and the most active users
+	Daily string
+	Users string
+	// Messages labels the axis of message counts
+	Messages string
+}
+
+type UserCount struct {
+	UserID int64 `bun:"user_id"`
+	Count  int   `bun:"count"`
+	// Name is how the user is shown, it is filled in by the caller
+	Name string `bun:"-"`
+}
+
+type DayCount struct {
+	Day   time.Time `bun:"day"`
+	Count int       `bun:"count"`
+}
+
+type HourCount struct {
+	Hour  int `bun:"hour"`
+	Count int `bun:"count"`
+}
+
+type StickerSetCount struct {
+	SetName string `bun:"set_name"`
+	Count   int    `bun:"count"`
+}
@@ -6,12 +6,36 @@ import (
 	"github.com/uptrace/bun"
 )
 
+type StickerType string
+
+const (
+	StickerTypeStatic   StickerType = "static"
+	StickerTypeAnimated StickerType = "animated"
+	StickerTypeVideo    StickerType = "video"
+)
+
+// Sticker is a sticker seen in a chat. The same sticker is stored once per
+// chat, keyed on its file_unique_id which unlike file_id never changes
 type Sticker struct {
-	bun.BaseModel `bun:"table:stickers,alias:s"`
+	bun.BaseModel `bun:"table:chat_stickers,alias:s"`
+
+	ChatID       int64       `bun:"chat_id,pk" json:"chat_id"`
+	FileUniqueID string      `bun:"file_unique_id,pk" json:"file_unique_id"`
+	FileID       string      `bun:"file_id,notnull" json:"file_id"`
+	SetName      string      `bun:"set_name" json:"set_name"`
+	Emoji        string      `bun:"emoji,notnull,default:''" json:"emoji"`
+	Type         StickerType `bun:"type,notnull,default:'static'" json:"type"`
+	UsageCount   int64       `bun:"usage_count,notnull,default:1" json:"usage_count"`
+	CreatedAt    time.Time   `bun:"created_at,notnull,default:now()" json:"created_at"`
+	LastSeenAt   time.Time   `bun:"last_seen_at,notnull,default:now()" json:"last_seen_at"`
+}
+
+// StickerWord counts how often a sticker was sent right after a word
+type StickerWord struct {
+	bun.BaseModel `bun:"table:sticker_words,alias:sw"`
 
-	ID        int64     `bun:"id,pk,autoincrement" json:"id"`
-	ChatID    int64     `bun:"chat_id,notnull" json:"chat_id"`
-	FileID    string    `bun:"file_id,notnull,unique" json:"file_id"`
-	SetName   string    `bun:"set_name" json:"set_name"`
-	CreatedAt time.Time `bun:"created_at,notnull,default:now()" json:"created_at"`
+	ChatID       int64  `bun:"chat_id,pk" json:"chat_id"`
+	Word         string `bun:"word,pk" json:"word"`
+	FileUniqueID string `bun:"file_unique_id,pk" json:"file_unique_id"`
+	Count        int64  `bun:"count,notnull,default:1" json:"count"`
 }
@@ -0,0 +1,116 @@
+// Package i18n holds the texts the bot sends in every language it speaks
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultLocale is used for chats speaking a language without a bundle
+const DefaultLocale = "ru"
+
+//go:embed locales/*.json
+var files embed.FS
+
+// message is a text, or a set of texts by plural form for messages
+// depending on a number
+type message struct {
+	text  string
+	forms map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &m.forms)
+}
+
+// bundles maps a locale to its messages by key
+var bundles = mustLoad()
+
+// mustLoad reads the bundles. A message missing in a bundle falls back to
+// the default locale, the tests make sure there is none.
+func mustLoad() map[string]map[string]message {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read locales: %v", err))
+	}
+
+	loaded := make(map[string]map[string]message, len(entries))
+	for _, entry := range entries {
+		data, err := files.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", entry.Name(), err))
+		}
+
+		var messages map[string]message
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: failed to parse %s: %v", entry.Name(), err))
+		}
+
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	return loaded
+}
+
+// Locales returns the locales there are bundles for
+func Locales() []string {
+	locales := make([]string, 0, len(bundles))
+	for locale := range bundles {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+	return locales
+}
+
+// Locale returns the locale texts are shown in for the language
+func Locale(lang string) string {
+	if locale, ok := Match(lang); ok {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// Match returns the locale of a language tag like "en" or "en-US", false if
+// there is no bundle for it
+func Match(tag string) (string, bool) {
+	lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	_, exists := bundles[lang]
+	return lang, exists
+}
+
+// T returns the message of the locale formatted with args, falling back to
+// the default locale and then to the key itself
+func T(locale, key string, args ...any) string {
+	return N(locale, key, 0, args...)
+}
+
+// N returns the form of the message for the number n formatted with args
+func N(locale, key string, n int, args ...any) string {
+	msg, exists := bundles[locale][key]
+	if !exists {
+		locale = DefaultLocale
+		msg, exists = bundles[locale][key]
+	}
+	if !exists {
+		return key
+	}
+
+	if msg.forms == nil {
+		return format(msg.text, args)
+	}
+	return format(msg.forms[pluralForm(locale, n)], args)
+}
+
+func format(text string, args []any) string {
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
@@ -0,0 +1,48 @@
+package i18n
+
+import "testing"
+
+func TestDefaultLocaleLoaded(t *testing.T) {
+	if _, exists := bundles[DefaultLocale]; !exists {
+		t.Fatalf("no bundle for the default locale %q", DefaultLocale)
+	}
+}
+
+// TestBundlesComplete makes sure every bundle has the messages of the
+// default locale and no others, with all the plural forms of its language
+func TestBundlesComplete(t *testing.T) {
+	base := bundles[DefaultLocale]
+
+	for locale, messages := range bundles {
+		t.Run(locale, func(t *testing.T) {
+			for key := range base {
+				if _, exists := messages[key]; !exists {
+					t.Errorf("missing %q", key)
+				}
+			}
+
+			for key, msg := range messages {
+				if _, exists := base[key]; !exists {
+					t.Errorf("unknown %q", key)
+				}
+				if msg.forms == nil {
+					continue
+				}
+				for _, form := range pluralForms(locale) {
+					if _, exists := msg.forms[form]; !exists {
+						t.Errorf("%q has no %q form", key, form)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFallback(t *testing.T) {
+	if got := T("xx", "chart.daily"); got != T(DefaultLocale, "chart.daily") {
+		t.Errorf("T for an unknown locale = %q, want the default locale's text", got)
+	}
+	if got := T(DefaultLocale, "no.such.key"); got != "no.such.key" {
+		t.Errorf("T for an unknown key = %q, want the key", got)
+	}
+}
@@ -0,0 +1,36 @@
+package i18n
+
+// pluralForms returns the plural forms of the language, following the
+// CLDR names for them
+func pluralForms(locale string) []string {
+	switch locale {
+	case "ru", "uk":
+		return []string{"one", "few", "many"}
+	default:
+		return []string{"one", "other"}
+	}
+}
+
+// pluralForm returns the plural form of the language used for n
+func pluralForm(locale string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+
+	switch locale {
+	case "ru", "uk":
+		switch mod10, mod100 := n%10, n%100; {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
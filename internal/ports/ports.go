@@ -2,25 +2,110 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"io"
 	"time"
 
 	"github.com/malinatrash/egonez/internal/entity"
 )
 
+// ErrNoChartData is returned by a ChartRenderer when the statistics have
+// nothing to draw
+var ErrNoChartData = errors.New("no data to draw")
+
 type (
 	StickerRepository interface {
 		Create(ctx context.Context, sticker *entity.Sticker) error
+		Get(ctx context.Context, chatID int64, fileUniqueID string) (*entity.Sticker, error)
 		GetRandom(ctx context.Context, chatID int64) (*entity.Sticker, error)
+		GetRandomByEmoji(ctx context.Context, chatID int64, emoji string) (*entity.Sticker, error)
+		GetByWords(ctx context.Context, chatID int64, words []string, minCount int) (*entity.Sticker, error)
+		AddWords(ctx context.Context, chatID int64, fileUniqueID string, words []string) error
 		CountByChatID(ctx context.Context, chatID int64) (int, error)
 		DeleteAll(ctx context.Context, chatID int64) (int64, error)
 	}
 
+	AnimationRepository interface {
+		Create(ctx context.Context, animation *entity.Animation) error
+		Get(ctx context.Context, chatID int64, fileUniqueID string) (*entity.Animation, error)
+	}
+
 	MessageRepository interface {
 		Create(ctx context.Context, message *entity.Message) error
+		CreateBatch(ctx context.Context, messages []*entity.Message) error
+		UpdateText(ctx context.Context, chatID, messageID int64, text string) (int64, error)
 		GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*entity.Message, error)
+		GetByThread(ctx context.Context, chatID, threadID int64, limit, offset int) ([]*entity.Message, error)
 		CountByChatID(ctx context.Context, chatID int64) (int, error)
 		DeleteOlderThan(ctx context.Context, chatID int64, beforeTime time.Time) (int64, error)
+		DeleteBeyond(ctx context.Context, chatID int64, keep int) (int64, error)
 		GetRandom(ctx context.Context, chatID int64) (*entity.Message, error)
 		GetAllChatIDs(ctx context.Context) ([]int64, error)
+		GetActiveChatIDs(ctx context.Context, since time.Time, limit int) ([]int64, error)
+		GetUserChats(ctx context.Context, userID int64, limit int) ([]entity.UserChat, error)
+	}
+
+	GenerationRepository interface {
+		Create(ctx context.Context, generation *entity.Generation) error
+		GetByMessage(ctx context.Context, chatID, messageID int64) (*entity.Generation, error)
+		GetRated(ctx context.Context, chatID, chainThreadID int64, limit int) ([]*entity.Generation, error)
+		Replace(ctx context.Context, id int64, text string) error
+		Vote(ctx context.Context, id, userID int64, value int) (before, after int, err error)
+		SetVote(ctx context.Context, id, userID int64, value int, source entity.VoteSource) (before, after int, err error)
+		GetTop(ctx context.Context, chatID int64, limit int) ([]*entity.Generation, error)
+		MarkDeleted(ctx context.Context, id int64) error
+	}
+
+	QuoteRepository interface {
+		Create(ctx context.Context, quote *entity.Quote) (bool, error)
+		GetRandom(ctx context.Context, chatID int64) (*entity.Quote, error)
+		Search(ctx context.Context, chatID int64, term string, limit int) ([]*entity.Quote, error)
+		GetTexts(ctx context.Context, chatID, threadID int64, limit int) ([]string, error)
+	}
+
+	ScheduleRepository interface {
+		Save(ctx context.Context, schedule *entity.Schedule) error
+		GetByChatID(ctx context.Context, chatID int64) ([]*entity.Schedule, error)
+		ClaimDue(ctx context.Context, now time.Time, limit int, next func(schedule *entity.Schedule) (time.Time, bool)) ([]*entity.Schedule, error)
+		Delete(ctx context.Context, chatID int64, job entity.JobKind) (int64, error)
+	}
+
+	StatsRepository interface {
+		CountMessages(ctx context.Context, chatID int64, since time.Time) (int, error)
+		CountStickers(ctx context.Context, chatID int64, since time.Time) (int, error)
+		TopWords(ctx context.Context, chatID int64, since time.Time, stopWords []string, minLength, limit int) ([]entity.WordCount, error)
+		TopUsers(ctx context.Context, chatID int64, since time.Time, limit int) ([]entity.UserCount, error)
+		Daily(ctx context.Context, chatID int64, since time.Time) ([]entity.DayCount, error)
+		Hourly(ctx context.Context, chatID int64, since time.Time) ([]entity.HourCount, error)
+		StickerSets(ctx context.Context, chatID int64, since time.Time, limit int) ([]entity.StickerSetCount, error)
+	}
+
+	ChatSettingsRepository interface {
+		Get(ctx context.Context, chatID int64) (*entity.ChatSettings, error)
+		Save(ctx context.Context, settings *entity.ChatSettings) error
+		GetWithRetention(ctx context.Context) ([]*entity.ChatSettings, error)
+	}
+
+	// ChartRenderer draws chat statistics as a PNG image
+	ChartRenderer interface {
+		Render(stats *entity.ChatStats, labels entity.ChartLabels) ([]byte, error)
+	}
+
+	// Transcriber converts speech to text, the language is a hint and may
+	// be empty
+	Transcriber interface {
+		Transcribe(ctx context.Context, audio io.Reader, language string) (string, error)
+	}
+
+	// FileDownloader fetches files attached to Telegram messages
+	FileDownloader interface {
+		Download(ctx context.Context, fileID string) (io.ReadCloser, error)
+	}
+
+	RuleRepository interface {
+		Create(ctx context.Context, rule *entity.Rule) error
+		GetByChatID(ctx context.Context, chatID int64) ([]*entity.Rule, error)
+		CountByChatID(ctx context.Context, chatID int64) (int, error)
+		Delete(ctx context.Context, chatID, id int64) (int64, error)
 	}
 )
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.AnimationRepository = (*Animation)(nil)
+
+type Animation struct {
+	db *bun.DB
+}
+
+func NewAnimation(db *bun.DB) ports.AnimationRepository {
+	return &Animation{db: db}
+}
+
+// Create stores the animation for its chat or counts one more use of an
+// already known one
+func (r *Animation) Create(ctx context.Context, animation *entity.Animation) error {
+	_, err := r.db.NewInsert().
+		Model(animation).
+		On("CONFLICT (chat_id, file_unique_id) DO UPDATE").
+		Set("usage_count = a.usage_count + 1").
+		Set("last_seen_at = now()").
+		Set("file_id = EXCLUDED.file_id").
+		Exec(ctx)
+	return err
+}
+
+func (r *Animation) Get(ctx context.Context, chatID int64, fileUniqueID string) (*entity.Animation, error) {
+	var animation entity.Animation
+
+	err := r.db.NewSelect().
+		Model(&animation).
+		Where("chat_id = ? AND file_unique_id = ?", chatID, fileUniqueID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &animation, nil
+}
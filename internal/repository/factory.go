@@ -19,3 +19,31 @@ func (f *factory) newMessageRepository() ports.MessageRepository {
 func (f *factory) newStickerRepository() ports.StickerRepository {
 	return NewSticker(f.deps.DB)
 }
+
+func (f *factory) newAnimationRepository() ports.AnimationRepository {
+	return NewAnimation(f.deps.DB)
+}
+
+func (f *factory) newStatsRepository() ports.StatsRepository {
+	return NewStats(f.deps.DB)
+}
+
+func (f *factory) newScheduleRepository() ports.ScheduleRepository {
+	return NewSchedule(f.deps.DB)
+}
+
+func (f *factory) newRuleRepository() ports.RuleRepository {
+	return NewRule(f.deps.DB)
+}
+
+func (f *factory) newChatSettingsRepository() ports.ChatSettingsRepository {
+	return NewChatSettings(f.deps.DB)
+}
+
+func (f *factory) newGenerationRepository() ports.GenerationRepository {
+	return NewGeneration(f.deps.DB)
+}
+
+func (f *factory) newQuoteRepository() ports.QuoteRepository {
+	return NewQuote(f.deps.DB)
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.GenerationRepository = (*Generation)(nil)
+
+type Generation struct {
+	db *bun.DB
+}
+
+func NewGeneration(db *bun.DB) *Generation {
+	return &Generation{db: db}
+}
+
+func (r *Generation) Create(ctx context.Context, generation *entity.Generation) error {
+	_, err := r.db.NewInsert().
+		Model(generation).
+		Returning("id").
+		Exec(ctx)
+	return err
+}
+
+func (r *Generation) GetByMessage(ctx context.Context, chatID, messageID int64) (*entity.Generation, error) {
+	var generation entity.Generation
+	err := r.db.NewSelect().
+		Model(&generation).
+		Where("chat_id = ? AND message_id = ?", chatID, messageID).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &generation, nil
+}
+
+// GetRated returns the latest generations of the chain having a non-zero
+// score
+func (r *Generation) GetRated(ctx context.Context, chatID, chainThreadID int64, limit int) ([]*entity.Generation, error) {
+	var generations []*entity.Generation
+	err := r.db.NewSelect().
+		Model(&generations).
+		Where("chat_id = ? AND chain_thread_id = ?", chatID, chainThreadID).
+		Where("likes <> dislikes").
+		Order("created_at DESC").
+		Limit(limit).
+		Scan(ctx)
+
+	return generations, err
+}
+
+// Replace sets a new text of the generation, dropping the votes given to
+// the old one
+func (r *Generation) Replace(ctx context.Context, id int64, text string) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*entity.Vote)(nil)).
+			Where("generation_id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*entity.Generation)(nil)).
+			Set("text = ?", text).
+			Set("likes = 0").
+			Set("dislikes = 0").
+			Where("id = ?", id).
+			Exec(ctx)
+		return err
+	})
+}
+
+// Vote records the vote of the user given with the buttons, voting the same
+// way again withdraws it. It returns the vote of the user before and after,
+// zero meaning none.
+func (r *Generation) Vote(ctx context.Context, id, userID int64, value int) (before, after int, err error) {
+	return r.vote(ctx, id, userID, entity.VoteButton, func(current *entity.Vote) int {
+		if current.Value == value {
+			return 0
+		}
+		return value
+	})
+}
+
+// SetVote records the vote of the user given in another way than the
+// buttons. Zero withdraws it, unless it was given in another way.
+func (r *Generation) SetVote(ctx context.Context, id, userID int64, value int, source entity.VoteSource) (before, after int, err error) {
+	return r.vote(ctx, id, userID, source, func(current *entity.Vote) int {
+		if value == 0 && current.Source != source {
+			return current.Value
+		}
+		return value
+	})
+}
+
+// vote replaces the vote of the user with the one decide returns for the
+// current vote and updates the counters of the generation
+func (r *Generation) vote(ctx context.Context, id, userID int64, source entity.VoteSource, decide func(current *entity.Vote) int) (before, after int, err error) {
+	err = r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// Votes on the generation are serialized by locking its row
+		var locked int64
+		err := tx.NewSelect().
+			Model((*entity.Generation)(nil)).
+			Column("id").
+			Where("id = ?", id).
+			For("UPDATE").
+			Scan(ctx, &locked)
+		if err != nil {
+			return err
+		}
+
+		vote := entity.Vote{GenerationID: id, UserID: userID}
+		err = tx.NewSelect().
+			Model(&vote).
+			WherePK().
+			Scan(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		before = vote.Value
+		after = decide(&vote)
+		if after == before {
+			return nil
+		}
+
+		if after == 0 {
+			_, err = tx.NewDelete().
+				Model(&vote).
+				WherePK().
+				Exec(ctx)
+		} else {
+			vote.Value = after
+			vote.Source = source
+			_, err = tx.NewInsert().
+				Model(&vote).
+				On("CONFLICT (generation_id, user_id) DO UPDATE").
+				Set("value = EXCLUDED.value").
+				Set("source = EXCLUDED.source").
+				Exec(ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		likes, dislikes := entity.VoteChange(before, after)
+		_, err = tx.NewUpdate().
+			Model((*entity.Generation)(nil)).
+			Set("likes = likes + ?", likes).
+			Set("dislikes = dislikes + ?", dislikes).
+			Where("id = ?", id).
+			Exec(ctx)
+		return err
+	})
+
+	return before, after, err
+}
+
+// GetTop returns the most liked generations of the chat still in it
+func (r *Generation) GetTop(ctx context.Context, chatID int64, limit int) ([]*entity.Generation, error) {
+	var generations []*entity.Generation
+	err := r.db.NewSelect().
+		Model(&generations).
+		Where("chat_id = ? AND NOT deleted AND likes > 0", chatID).
+		OrderExpr("likes DESC, likes - dislikes DESC, created_at DESC").
+		Limit(limit).
+		Scan(ctx)
+
+	return generations, err
+}
+
+func (r *Generation) MarkDeleted(ctx context.Context, id int64) error {
+	_, err := r.db.NewUpdate().
+		Model((*entity.Generation)(nil)).
+		Set("deleted = true").
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
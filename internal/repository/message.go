@@ -19,19 +19,67 @@ func NewMessage(db *bun.DB) *Message {
 	return &Message{db: db}
 }
 
+// upsertMessages stores a message edited after it was queued once, with
+// the latest text
+const upsertMessages = "CONFLICT (chat_id, message_id, source) WHERE message_id <> 0 DO UPDATE"
+
 func (r *Message) Create(ctx context.Context, message *entity.Message) error {
 	_, err := r.db.NewInsert().
 		Model(message).
+		On(upsertMessages).
+		Set("text = EXCLUDED.text").
+		Exec(ctx)
+	return err
+}
+
+func (r *Message) CreateBatch(ctx context.Context, messages []*entity.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	_, err := r.db.NewInsert().
+		Model(&messages).
+		On(upsertMessages).
+		Set("text = EXCLUDED.text").
 		Exec(ctx)
 	return err
 }
 
+// UpdateText replaces the text of the message, the media token stored for
+// it is kept
+func (r *Message) UpdateText(ctx context.Context, chatID, messageID int64, text string) (int64, error) {
+	res, err := r.db.NewUpdate().
+		Model((*entity.Message)(nil)).
+		Set("text = ?", text).
+		Where("chat_id = ? AND message_id = ?", chatID, messageID).
+		Where("source NOT IN (?)", bun.In(mediaSources)).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
 func (r *Message) GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*entity.Message, error) {
 	var messages []*entity.Message
 	err := r.db.NewSelect().
 		Model(&messages).
 		Where("chat_id = ?", chatID).
-		Order("created_at DESC").
+		OrderExpr("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+
+	return messages, err
+}
+
+func (r *Message) GetByThread(ctx context.Context, chatID, threadID int64, limit, offset int) ([]*entity.Message, error) {
+	var messages []*entity.Message
+	err := r.db.NewSelect().
+		Model(&messages).
+		Where("chat_id = ? AND thread_id = ?", chatID, threadID).
+		OrderExpr("created_at DESC, id DESC").
 		Limit(limit).
 		Offset(offset).
 		Scan(ctx)
@@ -58,11 +106,33 @@ func (r *Message) DeleteOlderThan(ctx context.Context, chatID int64, beforeTime
 	return res.RowsAffected()
 }
 
+// DeleteBeyond deletes all but the keep latest messages of the chat
+func (r *Message) DeleteBeyond(ctx context.Context, chatID int64, keep int) (int64, error) {
+	older := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		Column("id").
+		Where("chat_id = ?", chatID).
+		OrderExpr("created_at DESC, id DESC").
+		Offset(keep)
+
+	res, err := r.db.NewDelete().
+		Model((*entity.Message)(nil)).
+		Where("id IN (?)", older).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// GetRandom returns a random message of the chat, media aren't picked
 func (r *Message) GetRandom(ctx context.Context, chatID int64) (*entity.Message, error) {
 	var message entity.Message
 	err := r.db.NewSelect().
 		Model(&message).
 		Where("chat_id = ?", chatID).
+		Where("source NOT IN (?)", bun.In([]entity.MessageSource{entity.MessageSourceSticker, entity.MessageSourceAnimation})).
 		Order("RANDOM()").
 		Limit(1).
 		Scan(ctx)
@@ -76,7 +146,7 @@ func (r *Message) GetRandom(ctx context.Context, chatID int64) (*entity.Message,
 
 func (r *Message) GetAllChatIDs(ctx context.Context) ([]int64, error) {
 	var chatIDs []int64
-	
+
 	err := r.db.NewSelect().
 		Model((*entity.Message)(nil)).
 		ColumnExpr("DISTINCT chat_id").
@@ -89,3 +159,49 @@ func (r *Message) GetAllChatIDs(ctx context.Context) ([]int64, error) {
 
 	return chatIDs, nil
 }
+
+// GetActiveChatIDs returns the chats with the most messages since the time
+func (r *Message) GetActiveChatIDs(ctx context.Context, since time.Time, limit int) ([]int64, error) {
+	var chatIDs []int64
+
+	err := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		Column("chat_id").
+		Where("created_at >= ?", since).
+		Group("chat_id").
+		OrderExpr("COUNT(*) DESC").
+		Limit(limit).
+		Scan(ctx, &chatIDs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return chatIDs, nil
+}
+
+// GetUserChats returns the chats the user has written to with the topic of
+// the latest message, the most recently used first
+func (r *Message) GetUserChats(ctx context.Context, userID int64, limit int) ([]entity.UserChat, error) {
+	var chats []entity.UserChat
+
+	latest := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		DistinctOn("chat_id").
+		Column("chat_id", "thread_id", "created_at").
+		Where("user_id = ?", userID).
+		OrderExpr("chat_id, created_at DESC, id DESC")
+
+	err := r.db.NewSelect().
+		TableExpr("(?) AS latest", latest).
+		Column("chat_id", "thread_id").
+		OrderExpr("created_at DESC").
+		Limit(limit).
+		Scan(ctx, &chats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
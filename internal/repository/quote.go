@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.QuoteRepository = (*Quote)(nil)
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type Quote struct {
+	db *bun.DB
+}
+
+func NewQuote(db *bun.DB) *Quote {
+	return &Quote{db: db}
+}
+
+// Create saves the quote, it reports false if the message is already saved
+func (r *Quote) Create(ctx context.Context, quote *entity.Quote) (bool, error) {
+	res, err := r.db.NewInsert().
+		Model(quote).
+		On("CONFLICT (chat_id, message_id) DO NOTHING").
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := res.RowsAffected()
+	return created > 0, err
+}
+
+func (r *Quote) GetRandom(ctx context.Context, chatID int64) (*entity.Quote, error) {
+	var quote entity.Quote
+	err := r.db.NewSelect().
+		Model(&quote).
+		Where("chat_id = ?", chatID).
+		Order("RANDOM()").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quote, nil
+}
+
+// Search returns the latest quotes containing the term, ignoring case
+func (r *Quote) Search(ctx context.Context, chatID int64, term string, limit int) ([]*entity.Quote, error) {
+	var quotes []*entity.Quote
+	err := r.db.NewSelect().
+		Model(&quotes).
+		Where("chat_id = ?", chatID).
+		Where("text ILIKE ?", "%"+likeEscaper.Replace(term)+"%").
+		Order("sent_at DESC").
+		Limit(limit).
+		Scan(ctx)
+
+	return quotes, err
+}
+
+// GetTexts returns the texts of the latest quotes of the chat, or of a
+// single topic of it if threadID is not zero
+func (r *Quote) GetTexts(ctx context.Context, chatID, threadID int64, limit int) ([]string, error) {
+	var texts []string
+
+	query := r.db.NewSelect().
+		Model((*entity.Quote)(nil)).
+		Column("text").
+		Where("chat_id = ?", chatID)
+	if threadID != 0 {
+		query = query.Where("thread_id = ?", threadID)
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Scan(ctx, &texts)
+
+	return texts, err
+}
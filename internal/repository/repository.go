@@ -15,15 +15,29 @@ type Params struct {
 }
 
 type Repository struct {
-	MessageRepository ports.MessageRepository
-	StickerRepository ports.StickerRepository
+	MessageRepository    ports.MessageRepository
+	StickerRepository    ports.StickerRepository
+	AnimationRepository  ports.AnimationRepository
+	StatsRepository      ports.StatsRepository
+	ScheduleRepository   ports.ScheduleRepository
+	RuleRepository       ports.RuleRepository
+	SettingsRepository   ports.ChatSettingsRepository
+	GenerationRepository ports.GenerationRepository
+	QuoteRepository      ports.QuoteRepository
 }
 
 func NewRepository(deps Params) *Repository {
 	f := newFactory(deps)
 
 	return &Repository{
-		MessageRepository: f.newMessageRepository(),
-		StickerRepository: f.newStickerRepository(),
+		MessageRepository:    f.newMessageRepository(),
+		StickerRepository:    f.newStickerRepository(),
+		AnimationRepository:  f.newAnimationRepository(),
+		StatsRepository:      f.newStatsRepository(),
+		ScheduleRepository:   f.newScheduleRepository(),
+		RuleRepository:       f.newRuleRepository(),
+		SettingsRepository:   f.newChatSettingsRepository(),
+		GenerationRepository: f.newGenerationRepository(),
+		QuoteRepository:      f.newQuoteRepository(),
 	}
 }
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.RuleRepository = (*Rule)(nil)
+
+type Rule struct {
+	db *bun.DB
+}
+
+func NewRule(db *bun.DB) *Rule {
+	return &Rule{db: db}
+}
+
+func (r *Rule) Create(ctx context.Context, rule *entity.Rule) error {
+	_, err := r.db.NewInsert().
+		Model(rule).
+		Returning("id").
+		Exec(ctx)
+	return err
+}
+
+func (r *Rule) GetByChatID(ctx context.Context, chatID int64) ([]*entity.Rule, error) {
+	var rules []*entity.Rule
+	err := r.db.NewSelect().
+		Model(&rules).
+		Where("chat_id = ?", chatID).
+		Order("id").
+		Scan(ctx)
+
+	return rules, err
+}
+
+func (r *Rule) CountByChatID(ctx context.Context, chatID int64) (int, error) {
+	return r.db.NewSelect().
+		Model((*entity.Rule)(nil)).
+		Where("chat_id = ?", chatID).
+		Count(ctx)
+}
+
+func (r *Rule) Delete(ctx context.Context, chatID, id int64) (int64, error) {
+	res, err := r.db.NewDelete().
+		Model((*entity.Rule)(nil)).
+		Where("chat_id = ? AND id = ?", chatID, id).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.ScheduleRepository = (*Schedule)(nil)
+
+type Schedule struct {
+	db *bun.DB
+}
+
+func NewSchedule(db *bun.DB) *Schedule {
+	return &Schedule{db: db}
+}
+
+// Save creates the schedule or replaces the one of the same job in the chat
+func (r *Schedule) Save(ctx context.Context, schedule *entity.Schedule) error {
+	_, err := r.db.NewInsert().
+		Model(schedule).
+		On("CONFLICT (chat_id, job) DO UPDATE").
+		Set("thread_id = EXCLUDED.thread_id").
+		Set("spec = EXCLUDED.spec").
+		Set("arg = EXCLUDED.arg").
+		Set("next_run_at = EXCLUDED.next_run_at").
+		Set("created_by = EXCLUDED.created_by").
+		Returning("id").
+		Exec(ctx)
+	return err
+}
+
+func (r *Schedule) GetByChatID(ctx context.Context, chatID int64) ([]*entity.Schedule, error) {
+	var schedules []*entity.Schedule
+	err := r.db.NewSelect().
+		Model(&schedules).
+		Where("chat_id = ?", chatID).
+		Order("job").
+		Scan(ctx)
+
+	return schedules, err
+}
+
+// ClaimDue moves the schedules whose next run is not after now to the run
+// next returns for them, and returns them earliest first. Schedules locked
+// by another instance claiming them are skipped, so that every run is
+// claimed once. Schedules next reports false for are moved without being
+// claimed.
+func (r *Schedule) ClaimDue(ctx context.Context, now time.Time, limit int, next func(schedule *entity.Schedule) (time.Time, bool)) ([]*entity.Schedule, error) {
+	var claimed []*entity.Schedule
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var due []*entity.Schedule
+		err := tx.NewSelect().
+			Model(&due).
+			Where("next_run_at <= ?", now).
+			Order("next_run_at").
+			Limit(limit).
+			For("UPDATE SKIP LOCKED").
+			Scan(ctx)
+		if err != nil {
+			return err
+		}
+
+		claimed = claimed[:0]
+		for _, schedule := range due {
+			nextRun, ok := next(schedule)
+
+			query := tx.NewUpdate().
+				Model((*entity.Schedule)(nil)).
+				Set("next_run_at = ?", nextRun).
+				Where("id = ?", schedule.ID)
+			if ok {
+				query = query.Set("last_run_at = ?", now)
+			}
+			if _, err := query.Exec(ctx); err != nil {
+				return err
+			}
+
+			if ok {
+				claimed = append(claimed, schedule)
+			}
+		}
+		return nil
+	})
+
+	return claimed, err
+}
+
+func (r *Schedule) Delete(ctx context.Context, chatID int64, job entity.JobKind) (int64, error) {
+	res, err := r.db.NewDelete().
+		Model((*entity.Schedule)(nil)).
+		Where("chat_id = ? AND job = ?", chatID, job).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.ChatSettingsRepository = (*ChatSettings)(nil)
+
+type ChatSettings struct {
+	db *bun.DB
+}
+
+func NewChatSettings(db *bun.DB) *ChatSettings {
+	return &ChatSettings{db: db}
+}
+
+// Get returns the chat settings, or default ones if the chat has none stored
+func (r *ChatSettings) Get(ctx context.Context, chatID int64) (*entity.ChatSettings, error) {
+	settings := entity.ChatSettings{ChatID: chatID}
+	err := r.db.NewSelect().
+		Model(&settings).
+		WherePK().
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &entity.ChatSettings{ChatID: chatID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// GetWithRetention returns the settings of chats having a retention policy
+func (r *ChatSettings) GetWithRetention(ctx context.Context) ([]*entity.ChatSettings, error) {
+	var settings []*entity.ChatSettings
+	err := r.db.NewSelect().
+		Model(&settings).
+		Where("retention_days > 0 OR retention_messages > 0").
+		Order("chat_id").
+		Scan(ctx)
+
+	return settings, err
+}
+
+func (r *ChatSettings) Save(ctx context.Context, settings *entity.ChatSettings) error {
+	settings.UpdatedAt = time.Now()
+
+	_, err := r.db.NewInsert().
+		Model(settings).
+		On("CONFLICT (chat_id) DO UPDATE").
+		Exec(ctx)
+	return err
+}
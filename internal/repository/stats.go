@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/uptrace/bun"
+)
+
+var _ ports.StatsRepository = (*Stats)(nil)
+
+// mediaSources are the message sources excluded from the message
+// statistics, stickers are counted on their own
+var mediaSources = []entity.MessageSource{entity.MessageSourceSticker, entity.MessageSourceAnimation}
+
+type Stats struct {
+	db *bun.DB
+}
+
+func NewStats(db *bun.DB) ports.StatsRepository {
+	return &Stats{db: db}
+}
+
+func (r *Stats) CountMessages(ctx context.Context, chatID int64, since time.Time) (int, error) {
+	return r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		Where("chat_id = ? AND created_at >= ?", chatID, since).
+		Where("source NOT IN (?)", bun.In(mediaSources)).
+		Count(ctx)
+}
+
+// TopWords returns the most frequent words of at least minLength letters
+func (r *Stats) TopWords(ctx context.Context, chatID int64, since time.Time, stopWords []string, minLength, limit int) ([]entity.WordCount, error) {
+	var words []entity.WordCount
+
+	err := r.db.NewRaw(`
+		SELECT word, COUNT(*) AS count
+		FROM messages AS m, regexp_split_to_table(lower(m.text), '[^[:alnum:]]+') AS word
+		WHERE m.chat_id = ? AND m.created_at >= ? AND m.source NOT IN (?)
+			AND char_length(word) >= ? AND word NOT IN (?)
+		GROUP BY word
+		ORDER BY count DESC, word
+		LIMIT ?`,
+		chatID, since, bun.In(mediaSources), minLength, bun.In(stopWords), limit,
+	).Scan(ctx, &words)
+
+	return words, err
+}
+
+func (r *Stats) TopUsers(ctx context.Context, chatID int64, since time.Time, limit int) ([]entity.UserCount, error) {
+	var users []entity.UserCount
+
+	err := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		ColumnExpr("user_id, COUNT(*) AS count").
+		Where("chat_id = ? AND created_at >= ?", chatID, since).
+		Where("source NOT IN (?)", bun.In(mediaSources)).
+		Group("user_id").
+		OrderExpr("count DESC").
+		Limit(limit).
+		Scan(ctx, &users)
+
+	return users, err
+}
+
+func (r *Stats) Daily(ctx context.Context, chatID int64, since time.Time) ([]entity.DayCount, error) {
+	var days []entity.DayCount
+
+	err := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		ColumnExpr("date_trunc('day', created_at) AS day, COUNT(*) AS count").
+		Where("chat_id = ? AND created_at >= ?", chatID, since).
+		Where("source NOT IN (?)", bun.In(mediaSources)).
+		GroupExpr("day").
+		OrderExpr("day").
+		Scan(ctx, &days)
+
+	return days, err
+}
+
+func (r *Stats) Hourly(ctx context.Context, chatID int64, since time.Time) ([]entity.HourCount, error) {
+	var hours []entity.HourCount
+
+	err := r.db.NewSelect().
+		Model((*entity.Message)(nil)).
+		ColumnExpr("EXTRACT(HOUR FROM created_at)::int AS hour, COUNT(*) AS count").
+		Where("chat_id = ? AND created_at >= ?", chatID, since).
+		Where("source NOT IN (?)", bun.In(mediaSources)).
+		GroupExpr("hour").
+		OrderExpr("hour").
+		Scan(ctx, &hours)
+
+	return hours, err
+}
+
+// CountStickers returns the number of different stickers seen within the
+// period
+func (r *Stats) CountStickers(ctx context.Context, chatID int64, since time.Time) (int, error) {
+	return r.db.NewSelect().
+		Model((*entity.Sticker)(nil)).
+		Where("chat_id = ? AND last_seen_at >= ?", chatID, since).
+		Count(ctx)
+}
+
+// StickerSets returns the sticker sets used most, counting stickers last
+// seen within the period
+func (r *Stats) StickerSets(ctx context.Context, chatID int64, since time.Time, limit int) ([]entity.StickerSetCount, error) {
+	var sets []entity.StickerSetCount
+
+	err := r.db.NewSelect().
+		Model((*entity.Sticker)(nil)).
+		ColumnExpr("set_name, SUM(usage_count) AS count").
+		Where("chat_id = ? AND last_seen_at >= ? AND set_name <> ''", chatID, since).
+		Group("set_name").
+		OrderExpr("count DESC").
+		Limit(limit).
+		Scan(ctx, &sets)
+
+	return sets, err
+}
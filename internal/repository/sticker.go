@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 
-	"github.com/go-telegram/bot/models"
 	"github.com/malinatrash/egonez/internal/entity"
 	"github.com/malinatrash/egonez/internal/ports"
 	"github.com/uptrace/bun"
@@ -19,12 +18,61 @@ func NewSticker(db *bun.DB) ports.StickerRepository {
 	return &Sticker{db: db}
 }
 
+// Create stores the sticker for its chat or counts one more use of an
+// already known one, refreshing the file_id the sticker is sent with.
+// Stickers migrated without a file_unique_id are keyed on their file_id,
+// such a row is merged into the sticker once it is seen again.
 func (r *Sticker) Create(ctx context.Context, sticker *entity.Sticker) error {
-	_, err := r.db.NewInsert().
-		Model(sticker).
-		On("CONFLICT (file_id) DO NOTHING").
-		Exec(ctx)
-	return err
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var legacy []entity.Sticker
+		if sticker.FileID != sticker.FileUniqueID {
+			err := tx.NewDelete().
+				Model((*entity.Sticker)(nil)).
+				Where("chat_id = ? AND file_unique_id = ?", sticker.ChatID, sticker.FileID).
+				Returning("usage_count, created_at").
+				Scan(ctx, &legacy)
+			if err != nil {
+				return err
+			}
+		}
+
+		usage := int64(1)
+		for _, row := range legacy {
+			usage += row.UsageCount
+			if sticker.CreatedAt.IsZero() || row.CreatedAt.Before(sticker.CreatedAt) {
+				sticker.CreatedAt = row.CreatedAt
+			}
+		}
+		sticker.UsageCount = usage
+
+		_, err := tx.NewInsert().
+			Model(sticker).
+			On("CONFLICT (chat_id, file_unique_id) DO UPDATE").
+			Set("usage_count = s.usage_count + EXCLUDED.usage_count").
+			Set("created_at = LEAST(s.created_at, EXCLUDED.created_at)").
+			Set("last_seen_at = now()").
+			Set("file_id = EXCLUDED.file_id").
+			Set("set_name = EXCLUDED.set_name").
+			Set("emoji = EXCLUDED.emoji").
+			Set("type = EXCLUDED.type").
+			Exec(ctx)
+		return err
+	})
+}
+
+func (r *Sticker) Get(ctx context.Context, chatID int64, fileUniqueID string) (*entity.Sticker, error) {
+	var sticker entity.Sticker
+
+	err := r.db.NewSelect().
+		Model(&sticker).
+		Where("chat_id = ? AND file_unique_id = ?", chatID, fileUniqueID).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sticker, nil
 }
 
 func (r *Sticker) GetRandom(ctx context.Context, chatID int64) (*entity.Sticker, error) {
@@ -45,16 +93,86 @@ func (r *Sticker) GetRandom(ctx context.Context, chatID int64) (*entity.Sticker,
 	return &sticker, nil
 }
 
+func (r *Sticker) GetRandomByEmoji(ctx context.Context, chatID int64, emoji string) (*entity.Sticker, error) {
+	var sticker entity.Sticker
+
+	err := r.db.NewSelect().
+		Model(&sticker).
+		Where("chat_id = ? AND emoji = ?", chatID, emoji).
+		OrderExpr("RANDOM()").
+		Limit(1).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sticker, nil
+}
+
+// GetByWords returns the sticker most often sent after the words, counting
+// only stickers seen after them at least minCount times
+func (r *Sticker) GetByWords(ctx context.Context, chatID int64, words []string, minCount int) (*entity.Sticker, error) {
+	var sticker entity.Sticker
+
+	err := r.db.NewSelect().
+		Model(&sticker).
+		Join("JOIN sticker_words AS sw ON sw.chat_id = s.chat_id AND sw.file_unique_id = s.file_unique_id").
+		Where("s.chat_id = ?", chatID).
+		Where("sw.word IN (?)", bun.In(words)).
+		Group("s.chat_id", "s.file_unique_id").
+		Having("SUM(sw.count) >= ?", minCount).
+		OrderExpr("SUM(sw.count) DESC").
+		Limit(1).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &sticker, nil
+}
+
+func (r *Sticker) AddWords(ctx context.Context, chatID int64, fileUniqueID string, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+
+	links := make([]*entity.StickerWord, 0, len(words))
+	for _, word := range words {
+		links = append(links, &entity.StickerWord{
+			ChatID:       chatID,
+			Word:         word,
+			FileUniqueID: fileUniqueID,
+			Count:        1,
+		})
+	}
+
+	_, err := r.db.NewInsert().
+		Model(&links).
+		On("CONFLICT (chat_id, word, file_unique_id) DO UPDATE").
+		Set("count = sw.count + 1").
+		Exec(ctx)
+	return err
+}
+
 func (r *Sticker) CountByChatID(ctx context.Context, chatID int64) (int, error) {
 	return r.db.NewSelect().
-		Model((*models.Sticker)(nil)).
+		Model((*entity.Sticker)(nil)).
 		Where("chat_id = ?", chatID).
 		Count(ctx)
 }
 
 func (r *Sticker) DeleteAll(ctx context.Context, chatID int64) (int64, error) {
+	if _, err := r.db.NewDelete().
+		Model((*entity.StickerWord)(nil)).
+		Where("chat_id = ?", chatID).
+		Exec(ctx); err != nil {
+		return 0, err
+	}
+
 	res, err := r.db.NewDelete().
-		Model((*models.Sticker)(nil)).
+		Model((*entity.Sticker)(nil)).
 		Where("chat_id = ?", chatID).
 		Exec(ctx)
 	if err != nil {
@@ -0,0 +1,274 @@
+package scheduler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"go.uber.org/zap"
+)
+
+const (
+	// batchSize bounds the number of due schedules fetched per tick
+	batchSize = 100
+	// retryDelay postpones schedules that can't be run, so that they don't
+	// stay due and crowd out the others
+	retryDelay = time.Hour
+)
+
+var (
+	jobRuns     = expvar.NewMap("scheduler_job_runs_total")
+	jobFailures = expvar.NewMap("scheduler_job_failures_total")
+	jobSkipped  = expvar.NewMap("scheduler_job_skipped_total")
+)
+
+// Job runs a scheduled job for the chat of the schedule
+type Job func(ctx context.Context, schedule *entity.Schedule) error
+
+// task is a job of the bot itself run every interval, not stored in the
+// database
+type task struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	next     time.Time
+	// running is guarded by the mutex of the scheduler
+	running bool
+}
+
+// Scheduler runs the jobs of persisted schedules when they are due. Due
+// schedules are claimed in the database, so that instances sharing it run
+// every run once. A schedule never runs twice at the same time, a run
+// still in progress when the schedule is due again is skipped.
+type Scheduler struct {
+	repo    ports.ScheduleRepository
+	logger  *zap.Logger
+	loc     *time.Location
+	tick    time.Duration
+	timeout time.Duration
+
+	jobs  map[entity.JobKind]Job
+	tasks []*task
+
+	mu      sync.Mutex
+	running map[int64]bool
+	wg      sync.WaitGroup
+}
+
+func New(repo ports.ScheduleRepository, cfg config.SchedulerConfig, logger *zap.Logger) (*Scheduler, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", cfg.Timezone, err)
+	}
+
+	return &Scheduler{
+		repo:    repo,
+		logger:  logger.With(zap.String("service", "scheduler")),
+		loc:     loc,
+		tick:    cfg.Tick,
+		timeout: cfg.JobTimeout,
+		jobs:    make(map[entity.JobKind]Job),
+		running: make(map[int64]bool),
+	}, nil
+}
+
+// Location returns the time zone schedules are written in
+func (s *Scheduler) Location() *time.Location {
+	return s.loc
+}
+
+// Register sets the function running jobs of the kind
+func (s *Scheduler) Register(kind entity.JobKind, job Job) {
+	s.jobs[kind] = job
+}
+
+// Every runs the task every interval, starting an interval after Run. A run
+// still in progress when the task is due again is skipped.
+func (s *Scheduler) Every(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.tasks = append(s.tasks, &task{name: name, interval: interval, run: run})
+}
+
+// Run runs due jobs every tick until the context is done, then waits for
+// the jobs in progress
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	s.logger.Info("scheduler started", zap.Stringer("timezone", s.loc), zap.Duration("tick", s.tick))
+
+	for _, t := range s.tasks {
+		t.next = time.Now().Add(t.interval)
+	}
+
+	for {
+		// Schedules are only looked up if there are jobs to run them
+		if len(s.jobs) > 0 {
+			s.runDue(ctx)
+		}
+		s.runTasks(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context) {
+	now := time.Now()
+
+	// Schedules are moved forward as they are claimed, so that they aren't
+	// picked up again on the next tick or by another instance
+	schedules, err := s.repo.ClaimDue(ctx, now, batchSize, func(schedule *entity.Schedule) (time.Time, bool) {
+		next, err := s.next(schedule, now)
+		if err != nil {
+			retry := now.Add(retryDelay)
+			s.scheduleLogger(schedule).Error("can't run schedule, postponed", zap.Time("retry_at", retry), zap.Error(err))
+			return retry, false
+		}
+		return next, true
+	})
+	if err != nil {
+		s.logger.Error("failed to claim due schedules", zap.Error(err))
+		return
+	}
+
+	for _, schedule := range schedules {
+		logger := s.scheduleLogger(schedule)
+
+		if !s.acquire(schedule.ID) {
+			jobSkipped.Add(string(schedule.Job), 1)
+			logger.Warn("previous run still in progress, skipping")
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.run(ctx, s.jobs[schedule.Job], schedule, logger)
+	}
+}
+
+// next returns the run of the schedule following now, it fails for
+// schedules that can't be run
+func (s *Scheduler) next(schedule *entity.Schedule, now time.Time) (time.Time, error) {
+	if _, exists := s.jobs[schedule.Job]; !exists {
+		return time.Time{}, fmt.Errorf("no job %q registered", schedule.Job)
+	}
+
+	spec, err := ParseSpec(schedule.Spec, s.loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad schedule spec %q: %w", schedule.Spec, err)
+	}
+
+	return spec.Next(now), nil
+}
+
+func (s *Scheduler) scheduleLogger(schedule *entity.Schedule) *zap.Logger {
+	return s.logger.With(
+		zap.Int64("schedule_id", schedule.ID),
+		zap.Int64("chat_id", schedule.ChatID),
+		zap.String("job", string(schedule.Job)),
+	)
+}
+
+// runTasks starts the tasks that are due
+func (s *Scheduler) runTasks(ctx context.Context) {
+	now := time.Now()
+
+	for _, t := range s.tasks {
+		if now.Before(t.next) {
+			continue
+		}
+		t.next = now.Add(t.interval)
+
+		logger := s.logger.With(zap.String("task", t.name))
+		if !s.acquireTask(t) {
+			jobSkipped.Add(t.name, 1)
+			logger.Warn("previous run still in progress, skipping")
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.releaseTask(t)
+			s.execute(ctx, t.name, logger, t.run)
+		}()
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job, schedule *entity.Schedule, logger *zap.Logger) {
+	defer s.wg.Done()
+	defer s.release(schedule.ID)
+
+	s.execute(ctx, string(schedule.Job), logger, func(ctx context.Context) error {
+		return job(ctx, schedule)
+	})
+}
+
+// execute runs the job named name within the job timeout, counting its runs
+// and failures
+func (s *Scheduler) execute(ctx context.Context, name string, logger *zap.Logger, job func(ctx context.Context) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jobFailures.Add(name, 1)
+			logger.Error("job panicked", zap.Any("panic", r))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	jobRuns.Add(name, 1)
+
+	if err := job(ctx); err != nil {
+		jobFailures.Add(name, 1)
+		logger.Error("job failed", zap.Error(err))
+		return
+	}
+
+	logger.Info("job done", zap.Duration("duration", time.Since(start)))
+}
+
+func (s *Scheduler) acquire(id int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[id] {
+		return false
+	}
+	s.running[id] = true
+	return true
+}
+
+func (s *Scheduler) release(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, id)
+}
+
+func (s *Scheduler) acquireTask(t *task) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.running {
+		return false
+	}
+	t.running = true
+	return true
+}
+
+func (s *Scheduler) releaseTask(t *task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.running = false
+}
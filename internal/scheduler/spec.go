@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinInterval bounds how often an interval job may run
+const MinInterval = 10 * time.Minute
+
+// ErrInvalidSpec is returned for a schedule spec that can't be parsed
+var ErrInvalidSpec = errors.New("invalid schedule spec")
+
+// Spec tells when a job runs next
+type Spec interface {
+	// Next returns the first run time strictly after t
+	Next(t time.Time) time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "вс": time.Sunday,
+	"mon": time.Monday, "пн": time.Monday,
+	"tue": time.Tuesday, "вт": time.Tuesday,
+	"wed": time.Wednesday, "ср": time.Wednesday,
+	"thu": time.Thursday, "чт": time.Thursday,
+	"fri": time.Friday, "пт": time.Friday,
+	"sat": time.Saturday, "сб": time.Saturday,
+}
+
+// ParseSpec parses one of
+//
+//	HH:MM             every day at the time
+//	<weekday> HH:MM   every week, e.g. "mon 10:00" or "пн 10:00"
+//	every <duration>  at a fixed interval, e.g. "every 6h"
+//
+// Times are in loc.
+func ParseSpec(spec string, loc *time.Location) (Spec, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+
+	switch {
+	case len(fields) == 1:
+		hour, minute, err := parseClock(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		return daily{hour: hour, minute: minute, loc: loc}, nil
+	case len(fields) == 2 && fields[0] == "every":
+		interval, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad interval %q", ErrInvalidSpec, fields[1])
+		}
+		if interval < MinInterval {
+			return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalidSpec, MinInterval)
+		}
+		return every{interval: interval}, nil
+	case len(fields) == 2:
+		weekday, ok := weekdays[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidSpec, fields[0])
+		}
+		hour, minute, err := parseClock(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		return weekly{weekday: weekday, daily: daily{hour: hour, minute: minute, loc: loc}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSpec, spec)
+	}
+}
+
+func parseClock(s string) (int, int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: bad time %q, expected HH:MM", ErrInvalidSpec, s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+type daily struct {
+	hour, minute int
+	loc          *time.Location
+}
+
+func (d daily) Next(t time.Time) time.Time {
+	t = t.In(d.loc)
+	next := time.Date(t.Year(), t.Month(), t.Day(), d.hour, d.minute, 0, 0, d.loc)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type weekly struct {
+	weekday time.Weekday
+	daily
+}
+
+func (w weekly) Next(t time.Time) time.Time {
+	next := w.daily.Next(t)
+	for next.Weekday() != w.weekday {
+		next = w.daily.Next(next)
+	}
+	return next
+}
+
+type every struct {
+	interval time.Duration
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(e.interval)
+}
@@ -0,0 +1,24 @@
+package transcriber
+
+import (
+	"context"
+	"io"
+
+	"github.com/malinatrash/egonez/internal/ports"
+)
+
+var _ ports.Transcriber = (*Stub)(nil)
+
+// Stub returns a fixed text for any audio, for use in tests
+type Stub struct {
+	Text string
+	Err  error
+}
+
+func (s *Stub) Transcribe(ctx context.Context, audio io.Reader, language string) (string, error) {
+	if _, err := io.Copy(io.Discard, audio); err != nil {
+		return "", err
+	}
+
+	return s.Text, s.Err
+}
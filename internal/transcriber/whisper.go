@@ -0,0 +1,102 @@
+// Package transcriber contains ports.Transcriber implementations.
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/ports"
+)
+
+var _ ports.Transcriber = (*Whisper)(nil)
+
+// Whisper transcribes audio with a local whisper.cpp binary, the audio is
+// converted to the 16 kHz mono WAV it expects with ffmpeg first. Audio of a
+// chat without a language is transcribed in the configured one.
+type Whisper struct {
+	binary   string
+	model    string
+	language string
+	threads  int
+	ffmpeg   string
+}
+
+func NewWhisper(cfg config.TranscriberConfig) *Whisper {
+	return &Whisper{
+		binary:   cfg.Binary,
+		model:    cfg.Model,
+		language: cfg.Language,
+		threads:  cfg.Threads,
+		ffmpeg:   cfg.FFmpeg,
+	}
+}
+
+func (w *Whisper) Transcribe(ctx context.Context, audio io.Reader, language string) (string, error) {
+	if language == "" {
+		language = w.language
+	}
+
+	dir, err := os.MkdirTemp("", "egonez-voice-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input")
+	wav := filepath.Join(dir, "input.wav")
+
+	f, err := os.Create(input)
+	if err != nil {
+		return "", fmt.Errorf("failed to create input file: %w", err)
+	}
+	_, err = io.Copy(f, audio)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to write input file: %w", err)
+	}
+
+	if err := run(ctx, nil, w.ffmpeg,
+		"-loglevel", "error", "-y",
+		"-i", input,
+		"-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le",
+		wav,
+	); err != nil {
+		return "", fmt.Errorf("failed to convert audio: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := run(ctx, &out, w.binary,
+		"-m", w.model,
+		"-l", language,
+		"-t", strconv.Itoa(w.threads),
+		"-nt", "-np",
+		"-f", wav,
+	); err != nil {
+		return "", fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	return strings.Join(strings.Fields(out.String()), " "), nil
+}
+
+// run executes the command, its stderr is included in the returned error
+func run(ctx context.Context, stdout io.Writer, name string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -4,22 +4,96 @@ import (
 	"context"
 
 	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/pkg/markov"
 )
 
+// VoiceJob is a voice message waiting to be transcribed
+type VoiceJob struct {
+	Message    *entity.Message
+	FileID     string
+	Downloader ports.FileDownloader
+}
+
 type (
 	Bot interface {
-		HandleMessage(ctx context.Context, chatID, userID int64, text string) error
-		HandleSticker(ctx context.Context, chatID, userID int64, fileID, setName string) error
-		GenerateResponse(ctx context.Context, chatID int64) (string, error)
+		HandleMessage(ctx context.Context, message *entity.Message) error
+		HandleEdit(ctx context.Context, message *entity.Message) error
+		HandleSticker(ctx context.Context, sticker *entity.Sticker) error
+		HandleAnimation(ctx context.Context, animation *entity.Animation) error
+		GetMediaFileID(ctx context.Context, chatID int64, kind entity.MediaKind, fileUniqueID string) (string, error)
+		GenerateResponse(ctx context.Context, chatID, threadID int64, seed string) (string, error)
+		ChatLanguage(ctx context.Context, chatID int64) string
+		UserChats(ctx context.Context, userID int64, limit int) ([]entity.UserChat, error)
 		ClearChatHistory(ctx context.Context, chatID int64) error
-		GetRandomSticker(ctx context.Context, chatID int64) (*entity.Sticker, error)
-		GetChatStats(ctx context.Context, chatID int64) (*entity.ChatStats, error)
+		GetRandomSticker(ctx context.Context, chatID int64, emoji string) (*entity.Sticker, error)
+		PickSticker(ctx context.Context, chatID int64, text string) (*entity.Sticker, error)
+		GetChatStats(ctx context.Context, chatID int64, period entity.StatsPeriod) (*entity.ChatStats, error)
+		Retrain(ctx context.Context, chatID int64) error
+	}
+
+	Rules interface {
+		Match(ctx context.Context, chatID int64, text string, kinds ...entity.RuleMatchType) (*entity.RuleMatch, error)
+		AddRule(ctx context.Context, rule *entity.Rule) error
+		ListRules(ctx context.Context, chatID int64) ([]*entity.Rule, error)
+		DeleteRule(ctx context.Context, chatID, id int64) (bool, error)
+	}
+
+	Retention interface {
+		Sweep(ctx context.Context) (int64, error)
+		Apply(ctx context.Context, chatID int64) (int64, error)
+	}
+
+	Schedules interface {
+		List(ctx context.Context, chatID int64) ([]*entity.Schedule, error)
+		Set(ctx context.Context, schedule *entity.Schedule) error
+		Delete(ctx context.Context, chatID int64, job entity.JobKind) (bool, error)
+	}
+
+	Charts interface {
+		Render(stats *entity.ChatStats, labels entity.ChartLabels) ([]byte, error)
+	}
+
+	Ingest interface {
+		Enqueue(ctx context.Context, message *entity.Message, chainThreadID int64) error
+		Drain(ctx context.Context) error
+	}
+
+	Feedback interface {
+		Record(ctx context.Context, generation *entity.Generation) error
+		Get(ctx context.Context, chatID, messageID int64) (*entity.Generation, error)
+		Rate(ctx context.Context, generation *entity.Generation, userID int64, value int) (int, error)
+		React(ctx context.Context, generation *entity.Generation, userID int64, value int) (bool, error)
+		Top(ctx context.Context, chatID int64, limit int) ([]*entity.Generation, error)
+		Replace(ctx context.Context, generation *entity.Generation, text string) error
+		Delete(ctx context.Context, generation *entity.Generation) error
+	}
+
+	Quotes interface {
+		Save(ctx context.Context, quote *entity.Quote) (bool, error)
+		Random(ctx context.Context, chatID int64) (*entity.Quote, error)
+		Search(ctx context.Context, chatID int64, term string, limit int) ([]*entity.Quote, error)
+	}
+
+	Transcription interface {
+		Enqueue(job VoiceJob) bool
+		Drain(ctx context.Context) error
+	}
+
+	Settings interface {
+		Get(ctx context.Context, chatID int64) (*entity.ChatSettings, error)
+		Update(ctx context.Context, chatID int64, fn func(settings *entity.ChatSettings)) error
 	}
 
 	Markov interface {
-		Train(chatID int64, text string) error
-		Generate(chatID int64, prefix string, maxLength int) (string, error)
+		Train(chatID, threadID int64, text string) error
+		Quote(chatID, threadID int64, text string) error
+		Generate(chatID, threadID int64, prefix string, maxLength int, lang string) (string, error)
+		Language(chatID, threadID int64) string
+		Feedback(chatID, threadID int64, text string, before, after int)
 		Clear(chatID int64)
-		Load(ctx context.Context, chatID int64) error
+		Load(ctx context.Context, chatID, threadID int64) error
+		Ensure(ctx context.Context, chatID, threadID int64) error
+		GetChainStats(chatID int64) markov.ChainStats
 	}
 )
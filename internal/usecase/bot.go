@@ -2,66 +2,200 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/malinatrash/egonez/internal/entity"
 	"github.com/malinatrash/egonez/internal/ports"
 	"github.com/malinatrash/egonez/internal/usecase/adapters"
+	"github.com/malinatrash/egonez/pkg/markov"
+	"go.uber.org/zap"
 )
 
 var _ adapters.Bot = (*botService)(nil)
 
+var (
+	// ErrNotEnoughData is returned when the chat has nothing to learn from
+	ErrNotEnoughData = errors.New("not enough data")
+	// ErrWarmingUp is returned while the chains are loaded on start
+	ErrWarmingUp = errors.New("warming up")
+)
+
+const (
+	// stickerContextTTL is how long a text message stays the context for
+	// stickers sent after it
+	stickerContextTTL = 2 * time.Minute
+	// stickerMinWordLength skips short words carrying no meaning
+	stickerMinWordLength = 3
+	// stickerMaxWords bounds the words linked to a single sticker
+	stickerMaxWords = 10
+	// stickerMinCount is how many times a sticker has to follow the words
+	// before it is picked for them
+	stickerMinCount = 2
+)
+
+// lastText is the latest text message of a chat
+type lastText struct {
+	words []string
+	at    time.Time
+}
+
 type botService struct {
-	messageRepo   ports.MessageRepository
-	stickerRepo   ports.StickerRepository
-	markovService adapters.Markov
+	messageRepo     ports.MessageRepository
+	stickerRepo     ports.StickerRepository
+	animationRepo   ports.AnimationRepository
+	statsRepo       ports.StatsRepository
+	markovService   adapters.Markov
+	settingsService adapters.Settings
+	ingestService   adapters.Ingest
+	logger          *zap.Logger
+
+	mu       sync.Mutex
+	lastText map[int64]lastText
 }
 
 func NewBotService(
 	msgRepo ports.MessageRepository,
 	stickerRepo ports.StickerRepository,
+	animationRepo ports.AnimationRepository,
+	statsRepo ports.StatsRepository,
 	markovSvc adapters.Markov,
+	settingsSvc adapters.Settings,
+	ingestSvc adapters.Ingest,
+	logger *zap.Logger,
 ) adapters.Bot {
 	return &botService{
-		messageRepo:   msgRepo,
-		stickerRepo:   stickerRepo,
-		markovService: markovSvc,
+		messageRepo:     msgRepo,
+		stickerRepo:     stickerRepo,
+		animationRepo:   animationRepo,
+		statsRepo:       statsRepo,
+		markovService:   markovSvc,
+		settingsService: settingsSvc,
+		ingestService:   ingestSvc,
+		logger:          logger.With(zap.String("service", "bot")),
+		lastText:        make(map[int64]lastText),
 	}
 }
 
-func (s *botService) HandleMessage(ctx context.Context, chatID, userID int64, text string) error {
-	// Save the message to the database
-	message := &entity.Message{
-		ChatID: chatID,
-		UserID: userID,
-		Text:   text,
+func (s *botService) HandleMessage(ctx context.Context, message *entity.Message) error {
+	if message.Forwarded && !s.learnForwards(ctx, message.ChatID) {
+		return nil
+	}
+
+	if !message.Source.IsMedia() {
+		s.rememberText(message.ChatID, message.Text)
+	}
+
+	// The message is stored and learnt in the background
+	chainThreadID := s.chainThreadID(ctx, message.ChatID, message.ThreadID)
+	return s.ingestService.Enqueue(ctx, message, chainThreadID)
+}
+
+// HandleEdit replaces the text of an edited message and learns the new one.
+// An original not stored yet, e.g. still queued, is stored with the new
+// text in its place.
+func (s *botService) HandleEdit(ctx context.Context, message *entity.Message) error {
+	if message.Forwarded && !s.learnForwards(ctx, message.ChatID) {
+		return nil
 	}
 
-	if err := s.markovService.Train(chatID, text); err != nil {
-		fmt.Printf("Failed to train Markov model: %v\n", err)
+	updated, err := s.messageRepo.UpdateText(ctx, message.ChatID, message.MessageID, message.Text)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+
+	// The original isn't stored yet, or was sent before the bot joined
+	if updated == 0 {
+		return s.HandleMessage(ctx, message)
 	}
 
-	return s.messageRepo.Create(ctx, message)
+	chainThreadID := s.chainThreadID(ctx, message.ChatID, message.ThreadID)
+	if err := s.markovService.Train(message.ChatID, chainThreadID, message.Text); err != nil {
+		s.logger.Error("failed to train Markov model", zap.Int64("chat_id", message.ChatID), zap.Error(err))
+	}
+
+	return nil
 }
 
-func (s *botService) GenerateResponse(ctx context.Context, chatID int64) (string, error) {
-	err := s.markovService.Load(ctx, chatID)
+func (s *botService) learnForwards(ctx context.Context, chatID int64) bool {
+	settings, err := s.settingsService.Get(ctx, chatID)
+	return err == nil && settings.LearnForwards
+}
+
+// GenerateResponse returns ErrWarmingUp until the chains are loaded on
+// start and ErrNotEnoughData if the chat has nothing to learn from
+func (s *botService) GenerateResponse(ctx context.Context, chatID, threadID int64, seed string) (string, error) {
+	chainThreadID := s.chainThreadID(ctx, chatID, threadID)
+	err := s.markovService.Ensure(ctx, chatID, chainThreadID)
+	if errors.Is(err, markov.ErrWarmingUp) {
+		return "", ErrWarmingUp
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to load messages: %w", err)
 	}
 
-	response, err := s.markovService.Generate(chatID, "", 50) // Generate up to 50 words
+	var lang string
+	if settings, err := s.settingsService.Get(ctx, chatID); err == nil {
+		lang = settings.Language
+	}
+
+	response, err := s.markovService.Generate(chatID, chainThreadID, seed, 50, lang) // Generate up to 50 words
 	if err != nil {
-		if err.Error() == "no data available for generation" {
-			return "I don't have enough data to generate a response yet. Send me some messages first!", nil
+		if errors.Is(err, markov.ErrNoData) {
+			return "", ErrNotEnoughData
 		}
 		return "", fmt.Errorf("failed to generate response: %w", err)
 	}
+	if response == "" {
+		return "", ErrNotEnoughData
+	}
 
 	return response, nil
 }
 
+// ChatLanguage returns the language set for the chat, or the one detected
+// from its messages, empty if neither is known
+func (s *botService) ChatLanguage(ctx context.Context, chatID int64) string {
+	if settings, err := s.settingsService.Get(ctx, chatID); err == nil && settings.Language != "" {
+		return settings.Language
+	}
+	return s.markovService.Language(chatID, 0)
+}
+
+// UserChats returns the chats the user has written to, the most recently
+// used first
+func (s *botService) UserChats(ctx context.Context, userID int64, limit int) ([]entity.UserChat, error) {
+	chats, err := s.messageRepo.GetUserChats(ctx, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user chats: %w", err)
+	}
+	return chats, nil
+}
+
+func (s *botService) chainThreadID(ctx context.Context, chatID, threadID int64) int64 {
+	return chainThreadID(ctx, s.settingsService, chatID, threadID)
+}
+
+// chainThreadID returns the thread whose chain serves the topic, which is
+// the topic itself if the chat keeps chains per topic and zero otherwise
+func chainThreadID(ctx context.Context, settingsService adapters.Settings, chatID, threadID int64) int64 {
+	if threadID == 0 {
+		return 0
+	}
+
+	settings, err := settingsService.Get(ctx, chatID)
+	if err != nil || !settings.TopicChains {
+		return 0
+	}
+
+	return threadID
+}
+
 func (s *botService) ClearChatHistory(ctx context.Context, chatID int64) error {
 	_, err := s.messageRepo.DeleteOlderThan(ctx, chatID, time.Now().Add(-time.Second))
 	if err != nil {
@@ -72,8 +206,19 @@ func (s *botService) ClearChatHistory(ctx context.Context, chatID int64) error {
 	return nil
 }
 
-func (s *botService) GetRandomSticker(ctx context.Context, chatID int64) (*entity.Sticker, error) {
-	sticker, err := s.stickerRepo.GetRandom(ctx, chatID)
+// GetRandomSticker returns a random sticker of the chat, limited to the
+// emoji if one is given
+func (s *botService) GetRandomSticker(ctx context.Context, chatID int64, emoji string) (*entity.Sticker, error) {
+	var (
+		sticker *entity.Sticker
+		err     error
+	)
+
+	if emoji == "" {
+		sticker, err = s.stickerRepo.GetRandom(ctx, chatID)
+	} else {
+		sticker, err = s.stickerRepo.GetRandomByEmoji(ctx, chatID, NormalizeEmoji(emoji))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get random sticker: %w", err)
 	}
@@ -81,31 +226,171 @@ func (s *botService) GetRandomSticker(ctx context.Context, chatID int64) (*entit
 	return sticker, nil
 }
 
-func (s *botService) HandleSticker(ctx context.Context, chatID, userID int64, fileID, setName string) error {
-	// Create a new sticker entity
-	sticker := &entity.Sticker{
-		ChatID:  chatID,
-		FileID:  fileID,
-		SetName: setName,
+// PickSticker returns a sticker fitting the text, first by the emoji it
+// contains and then by the stickers the chat used to send after its words.
+// It returns nil if nothing fits
+func (s *botService) PickSticker(ctx context.Context, chatID int64, text string) (*entity.Sticker, error) {
+	for _, emoji := range textEmoji(text) {
+		sticker, err := s.stickerRepo.GetRandomByEmoji(ctx, chatID, emoji)
+		if err == nil {
+			return sticker, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get sticker by emoji: %w", err)
+		}
 	}
 
-	// Save the sticker to the database
-	return s.stickerRepo.Create(ctx, sticker)
-}
+	words := stickerWords(text)
+	if len(words) == 0 {
+		return nil, nil
+	}
 
-func (s *botService) GetChatStats(ctx context.Context, chatID int64) (*entity.ChatStats, error) {
-	messageCount, err := s.messageRepo.CountByChatID(ctx, chatID)
+	sticker, err := s.stickerRepo.GetByWords(ctx, chatID, words, stickerMinCount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get message count: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get sticker by words: %w", err)
 	}
 
-	stickerCount, err := s.stickerRepo.CountByChatID(ctx, chatID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get sticker count: %w", err)
+	return sticker, nil
+}
+
+// HandleSticker stores the sticker and links it to the words of the text
+// message it followed
+func (s *botService) HandleSticker(ctx context.Context, sticker *entity.Sticker) error {
+	if sticker.FileUniqueID == "" {
+		return fmt.Errorf("sticker %q has no file_unique_id", sticker.FileID)
 	}
 
-	return &entity.ChatStats{
-		MessageCount: messageCount,
-		StickerCount: stickerCount,
-	}, nil
+	sticker.Emoji = NormalizeEmoji(sticker.Emoji)
+
+	if err := s.stickerRepo.Create(ctx, sticker); err != nil {
+		return fmt.Errorf("failed to save sticker: %w", err)
+	}
+
+	words := s.recentWords(sticker.ChatID)
+	if err := s.stickerRepo.AddWords(ctx, sticker.ChatID, sticker.FileUniqueID, words); err != nil {
+		return fmt.Errorf("failed to link sticker words: %w", err)
+	}
+
+	return nil
+}
+
+func (s *botService) HandleAnimation(ctx context.Context, animation *entity.Animation) error {
+	if err := s.animationRepo.Create(ctx, animation); err != nil {
+		return fmt.Errorf("failed to save animation: %w", err)
+	}
+
+	return nil
+}
+
+// GetMediaFileID returns the file_id to send the media of a generated
+// media token with
+func (s *botService) GetMediaFileID(ctx context.Context, chatID int64, kind entity.MediaKind, fileUniqueID string) (string, error) {
+	switch kind {
+	case entity.MediaSticker:
+		sticker, err := s.stickerRepo.Get(ctx, chatID, fileUniqueID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get sticker: %w", err)
+		}
+		return sticker.FileID, nil
+	case entity.MediaAnimation:
+		animation, err := s.animationRepo.Get(ctx, chatID, fileUniqueID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get animation: %w", err)
+		}
+		return animation.FileID, nil
+	default:
+		return "", fmt.Errorf("unknown media kind %q", kind)
+	}
+}
+
+func (s *botService) rememberText(chatID int64, text string) {
+	words := stickerWords(text)
+	if len(words) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastText[chatID] = lastText{words: words, at: time.Now()}
+}
+
+// recentWords returns the words of the chat's last text message once, so
+// that a burst of stickers doesn't count the same context several times
+func (s *botService) recentWords(chatID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, exists := s.lastText[chatID]
+	delete(s.lastText, chatID)
+
+	if !exists || time.Since(last.at) > stickerContextTTL {
+		return nil
+	}
+
+	return last.words
+}
+
+// NormalizeEmoji drops variation selectors so that the same emoji sent
+// from different clients compares equal
+func NormalizeEmoji(emoji string) string {
+	return strings.ReplaceAll(strings.TrimSpace(emoji), "\uFE0F", "")
+}
+
+// textEmoji returns the distinct emoji found in the text
+func textEmoji(text string) []string {
+	var (
+		emoji []string
+		seen  = make(map[string]bool)
+	)
+
+	for _, r := range text {
+		if !unicode.Is(unicode.So, r) {
+			continue
+		}
+		e := string(r)
+		if !seen[e] {
+			seen[e] = true
+			emoji = append(emoji, e)
+		}
+	}
+
+	return emoji
+}
+
+// stickerWords returns the distinct lowercased words of the text worth
+// linking stickers to
+func stickerWords(text string) []string {
+	var (
+		words []string
+		seen  = make(map[string]bool)
+	)
+
+	for _, word := range SplitWords(strings.ToLower(text)) {
+		if len([]rune(word)) < stickerMinWordLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+		if len(words) == stickerMaxWords {
+			break
+		}
+	}
+
+	return words
+}
+
+// Retrain rebuilds the chains of the chat from the stored messages, which
+// also drops weight accumulated by repeated loads
+func (s *botService) Retrain(ctx context.Context, chatID int64) error {
+	s.markovService.Clear(chatID)
+
+	if err := s.markovService.Load(ctx, chatID, 0); err != nil {
+		return fmt.Errorf("failed to load messages: %w", err)
+	}
+
+	return nil
 }
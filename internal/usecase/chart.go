@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Charts = (*chartService)(nil)
+
+// ErrNoChartData is returned when there is no activity to draw
+var ErrNoChartData = errors.New("no chart data")
+
+type chartService struct {
+	renderer ports.ChartRenderer
+}
+
+func NewChartService(renderer ports.ChartRenderer) adapters.Charts {
+	return &chartService{renderer: renderer}
+}
+
+func (s *chartService) Render(stats *entity.ChatStats, labels entity.ChartLabels) ([]byte, error) {
+	image, err := s.renderer.Render(stats, labels)
+	if err != nil {
+		if errors.Is(err, ports.ErrNoChartData) {
+			return nil, ErrNoChartData
+		}
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return image, nil
+}
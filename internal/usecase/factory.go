@@ -1,8 +1,13 @@
 package usecase
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/malinatrash/egonez/config"
+	"github.com/malinatrash/egonez/internal/chart"
 	"github.com/malinatrash/egonez/internal/repository"
+	"github.com/malinatrash/egonez/internal/transcriber"
 	"github.com/malinatrash/egonez/internal/usecase/adapters"
 	"github.com/malinatrash/egonez/pkg/markov"
 	"go.uber.org/zap"
@@ -22,14 +27,104 @@ func NewServiceFactory(params Params) *ServiceFactory {
 	}
 }
 
-func (f *ServiceFactory) NewBotService() adapters.Bot {
+func (f *ServiceFactory) NewBotService(settings adapters.Settings, markov adapters.Markov, ingest adapters.Ingest) adapters.Bot {
 	return NewBotService(
 		f.repository.MessageRepository,
 		f.repository.StickerRepository,
-		f.newMarkovService(),
+		f.repository.AnimationRepository,
+		f.repository.StatsRepository,
+		markov,
+		settings,
+		ingest,
+		f.logger,
+	)
+}
+
+func (f *ServiceFactory) NewIngestService(markov adapters.Markov) adapters.Ingest {
+	cfg := f.config.IngestConfig
+
+	return NewIngestService(
+		f.repository.MessageRepository,
+		markov,
+		cfg.BatchSize,
+		cfg.QueueSize,
+		cfg.FlushInterval,
+		f.logger,
+	)
+}
+
+func (f *ServiceFactory) NewFeedbackService(markov adapters.Markov, settings adapters.Settings) adapters.Feedback {
+	return NewFeedbackService(f.repository.GenerationRepository, markov, settings)
+}
+
+func (f *ServiceFactory) NewQuoteService(markov adapters.Markov, settings adapters.Settings) adapters.Quotes {
+	return NewQuoteService(f.repository.QuoteRepository, f.repository.MessageRepository, markov, settings)
+}
+
+func (f *ServiceFactory) NewRuleService() adapters.Rules {
+	return NewRuleService(f.repository.RuleRepository, f.config.RuleConfig.MaxPerChat)
+}
+
+func (f *ServiceFactory) NewRetentionService(settings adapters.Settings, bot adapters.Bot) adapters.Retention {
+	return NewRetentionService(
+		f.repository.MessageRepository,
+		f.repository.SettingsRepository,
+		settings,
+		bot,
+		f.logger,
 	)
 }
 
-func (f *ServiceFactory) newMarkovService() adapters.Markov {
-	return markov.NewService(f.config.MarkovConfig.Order, f.repository.MessageRepository, f.logger)
+// NewScheduleService returns nil if the scheduler is disabled
+func (f *ServiceFactory) NewScheduleService() (adapters.Schedules, error) {
+	cfg := f.config.SchedulerConfig
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", cfg.Timezone, err)
+	}
+
+	return NewScheduleService(f.repository.ScheduleRepository, loc), nil
+}
+
+func (f *ServiceFactory) NewChartService() adapters.Charts {
+	return NewChartService(chart.NewPlot())
+}
+
+func (f *ServiceFactory) NewSettingsService() adapters.Settings {
+	return NewSettingsService(f.repository.SettingsRepository)
+}
+
+// NewTranscriptionService returns nil if transcription is disabled
+func (f *ServiceFactory) NewTranscriptionService(bot adapters.Bot) adapters.Transcription {
+	cfg := f.config.TranscriberConfig
+	if !cfg.Enabled {
+		return nil
+	}
+
+	return NewTranscriptionService(
+		transcriber.NewWhisper(cfg),
+		bot,
+		cfg.Workers,
+		cfg.QueueSize,
+		cfg.Timeout,
+		f.logger,
+	)
+}
+
+func (f *ServiceFactory) NewMarkovService() adapters.Markov {
+	cfg := f.config.MarkovConfig
+
+	return markov.NewService(markov.Options{
+		Order:         cfg.Order,
+		MaxChains:     cfg.MaxChains,
+		MaxTokens:     cfg.MaxTokens,
+		WarmUpChats:   cfg.WarmUpChats,
+		WarmUpWorkers: cfg.WarmUpWorkers,
+		Retries:       cfg.WarmUpRetries,
+		RetryBackoff:  cfg.WarmUpBackoff,
+	}, f.repository.MessageRepository, f.repository.GenerationRepository, f.repository.QuoteRepository, f.logger)
 }
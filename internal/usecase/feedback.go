@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Feedback = (*feedbackService)(nil)
+
+// feedbackService keeps the generated messages and feeds the ratings users
+// give them back into the chains
+type feedbackService struct {
+	generationRepo  ports.GenerationRepository
+	markovService   adapters.Markov
+	settingsService adapters.Settings
+}
+
+func NewFeedbackService(generationRepo ports.GenerationRepository, markovSvc adapters.Markov, settingsSvc adapters.Settings) adapters.Feedback {
+	return &feedbackService{
+		generationRepo:  generationRepo,
+		markovService:   markovSvc,
+		settingsService: settingsSvc,
+	}
+}
+
+// Record stores a generated message sent to the chat
+func (s *feedbackService) Record(ctx context.Context, generation *entity.Generation) error {
+	generation.ChainThreadID = chainThreadID(ctx, s.settingsService, generation.ChatID, generation.ThreadID)
+	if err := s.generationRepo.Create(ctx, generation); err != nil {
+		return fmt.Errorf("failed to store generation: %w", err)
+	}
+	return nil
+}
+
+// Get returns the generation sent as the message, nil if the message isn't
+// a generated one
+func (s *feedbackService) Get(ctx context.Context, chatID, messageID int64) (*entity.Generation, error) {
+	generation, err := s.generationRepo.GetByMessage(ctx, chatID, messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get generation: %w", err)
+	}
+	return generation, nil
+}
+
+// Rate records the vote of the user, 1 for a like and -1 for a dislike,
+// and updates the generation. Voting the same way again withdraws the vote.
+// It returns the vote of the user after voting.
+func (s *feedbackService) Rate(ctx context.Context, generation *entity.Generation, userID int64, value int) (int, error) {
+	before, after, err := s.generationRepo.Vote(ctx, generation.ID, userID, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to vote: %w", err)
+	}
+
+	s.applyVote(generation, before, after)
+	return after, nil
+}
+
+// React records the reaction of the user as a vote, 1 for a positive one,
+// -1 for a negative one and zero for none, and updates the generation. It
+// reports whether the vote of the user has changed.
+func (s *feedbackService) React(ctx context.Context, generation *entity.Generation, userID int64, value int) (bool, error) {
+	before, after, err := s.generationRepo.SetVote(ctx, generation.ID, userID, value, entity.VoteReaction)
+	if err != nil {
+		return false, fmt.Errorf("failed to vote: %w", err)
+	}
+
+	s.applyVote(generation, before, after)
+	return before != after, nil
+}
+
+// applyVote updates the counters of the generation and feeds the change of
+// its score into the chain
+func (s *feedbackService) applyVote(generation *entity.Generation, before, after int) {
+	score := generation.Score()
+	likes, dislikes := entity.VoteChange(before, after)
+	generation.Likes += likes
+	generation.Dislikes += dislikes
+	s.markovService.Feedback(generation.ChatID, generation.ChainThreadID, generation.Text, score, generation.Score())
+}
+
+// Top returns the most liked generations of the chat
+func (s *feedbackService) Top(ctx context.Context, chatID int64, limit int) ([]*entity.Generation, error) {
+	generations, err := s.generationRepo.GetTop(ctx, chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top generations: %w", err)
+	}
+	return generations, nil
+}
+
+// Replace sets a regenerated text, the score of the old one is taken back
+func (s *feedbackService) Replace(ctx context.Context, generation *entity.Generation, text string) error {
+	if err := s.generationRepo.Replace(ctx, generation.ID, text); err != nil {
+		return fmt.Errorf("failed to replace generation: %w", err)
+	}
+
+	s.markovService.Feedback(generation.ChatID, generation.ChainThreadID, generation.Text, generation.Score(), 0)
+	generation.Text = text
+	generation.Likes = 0
+	generation.Dislikes = 0
+	return nil
+}
+
+// Delete marks the generation deleted, its score is kept
+func (s *feedbackService) Delete(ctx context.Context, generation *entity.Generation) error {
+	if err := s.generationRepo.MarkDeleted(ctx, generation.ID); err != nil {
+		return fmt.Errorf("failed to delete generation: %w", err)
+	}
+	generation.Deleted = true
+	return nil
+}
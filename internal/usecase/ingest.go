@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+	"go.uber.org/zap"
+)
+
+var _ adapters.Ingest = (*ingestService)(nil)
+
+// flushTimeout bounds storing and learning a single batch
+const flushTimeout = 30 * time.Second
+
+var (
+	ingested      = expvar.NewMap("ingest_messages_total")
+	ingestBatches = expvar.NewInt("ingest_batches_total")
+	// ingestQueued is the number of messages waiting for a flush and
+	// ingestBlocked counts handlers that had to wait for a free slot
+	ingestQueued  = expvar.NewInt("ingest_queue_depth")
+	ingestBlocked = expvar.NewInt("ingest_blocked_total")
+)
+
+// messageKey identifies a stored message, which has a row per source
+type messageKey struct {
+	chatID    int64
+	messageID int64
+	source    entity.MessageSource
+}
+
+type ingestJob struct {
+	message       *entity.Message
+	chainThreadID int64
+}
+
+// ingestService stores incoming messages in batches and trains the chains
+// on them once stored, away from the update handlers
+type ingestService struct {
+	messageRepo   ports.MessageRepository
+	markovService adapters.Markov
+	jobs          chan ingestJob
+	batchSize     int
+	interval      time.Duration
+	logger        *zap.Logger
+
+	// mu guards closed, senders hold it for reading so that the queue is
+	// never closed under them
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func NewIngestService(
+	msgRepo ports.MessageRepository,
+	markovSvc adapters.Markov,
+	batchSize, queueSize int,
+	interval time.Duration,
+	logger *zap.Logger,
+) adapters.Ingest {
+	svc := &ingestService{
+		messageRepo:   msgRepo,
+		markovService: markovSvc,
+		jobs:          make(chan ingestJob, queueSize),
+		batchSize:     max(batchSize, 1),
+		interval:      interval,
+		logger:        logger.With(zap.String("service", "ingest")),
+		done:          make(chan struct{}),
+	}
+
+	go svc.run()
+
+	return svc
+}
+
+// Enqueue queues the message to be stored and learnt. It blocks while the
+// queue is full, and stores the message right away once draining started.
+// A message without a date is dated now, not when its batch is flushed.
+func (s *ingestService) Enqueue(ctx context.Context, message *entity.Message, chainThreadID int64) error {
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+
+	job := ingestJob{message: message, chainThreadID: chainThreadID}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return s.store(ctx, []ingestJob{job})
+	}
+
+	select {
+	case s.jobs <- job:
+	default:
+		ingestBlocked.Add(1)
+		select {
+		case s.jobs <- job:
+		case <-ctx.Done():
+			ingested.Add("dropped", 1)
+			return ctx.Err()
+		}
+	}
+
+	ingested.Add("queued", 1)
+	ingestQueued.Add(1)
+	return nil
+}
+
+// Drain stops accepting messages and waits until the queued ones are
+// stored
+func (s *ingestService) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.jobs)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *ingestService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	batch := make([]ingestJob, 0, s.batchSize)
+	for {
+		select {
+		case job, ok := <-s.jobs:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, job)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *ingestService) flush(batch []ingestJob) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	ingestQueued.Add(-int64(len(batch)))
+	ingestBatches.Add(1)
+
+	if err := s.store(ctx, batch); err != nil {
+		s.logger.Error("failed to flush messages", zap.Int("batch", len(batch)), zap.Error(err))
+	}
+}
+
+// store writes the messages and trains the chains on the stored ones. If
+// the batch insert fails, the messages are written one by one so that a
+// single bad row doesn't lose the rest.
+func (s *ingestService) store(ctx context.Context, batch []ingestJob) error {
+	// A message edited before its batch is flushed is queued twice, only
+	// the latest text is written since a row can't be upserted twice at once
+	messages := make([]*entity.Message, 0, len(batch))
+	index := make(map[messageKey]int, len(batch))
+	for _, job := range batch {
+		if job.message.MessageID != 0 {
+			key := messageKey{job.message.ChatID, job.message.MessageID, job.message.Source}
+			if i, exists := index[key]; exists {
+				messages[i] = job.message
+				continue
+			}
+			index[key] = len(messages)
+		}
+		messages = append(messages, job.message)
+	}
+
+	stored := batch
+	if err := s.messageRepo.CreateBatch(ctx, messages); err != nil {
+		if len(batch) == 1 {
+			ingested.Add("failed", 1)
+			return err
+		}
+
+		s.logger.Warn("batch insert failed, storing messages one by one", zap.Error(err))
+		stored = s.storeEach(ctx, batch)
+	}
+
+	ingested.Add("stored", int64(len(stored)))
+
+	for _, job := range stored {
+		if err := s.markovService.Train(job.message.ChatID, job.chainThreadID, job.message.Text); err != nil {
+			s.logger.Error("failed to train Markov model", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// storeEach writes the messages one at a time and returns the stored ones
+func (s *ingestService) storeEach(ctx context.Context, batch []ingestJob) []ingestJob {
+	stored := make([]ingestJob, 0, len(batch))
+	for _, job := range batch {
+		if err := s.messageRepo.Create(ctx, job.message); err != nil {
+			ingested.Add("failed", 1)
+			s.logger.Error("failed to store message",
+				zap.Int64("chat_id", job.message.ChatID),
+				zap.Int64("message_id", job.message.MessageID),
+				zap.Error(err),
+			)
+			continue
+		}
+		stored = append(stored, job)
+	}
+
+	return stored
+}
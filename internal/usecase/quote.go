@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Quotes = (*quoteService)(nil)
+
+// quoteService keeps the quote books of the chats
+type quoteService struct {
+	quoteRepo       ports.QuoteRepository
+	messageRepo     ports.MessageRepository
+	markovService   adapters.Markov
+	settingsService adapters.Settings
+}
+
+func NewQuoteService(
+	quoteRepo ports.QuoteRepository,
+	messageRepo ports.MessageRepository,
+	markovSvc adapters.Markov,
+	settingsSvc adapters.Settings,
+) adapters.Quotes {
+	return &quoteService{
+		quoteRepo:       quoteRepo,
+		messageRepo:     messageRepo,
+		markovService:   markovSvc,
+		settingsService: settingsSvc,
+	}
+}
+
+// Save adds the quote to the book and teaches it to the chain with a higher
+// weight. It reports false if the message is already in the book.
+func (s *quoteService) Save(ctx context.Context, quote *entity.Quote) (bool, error) {
+	created, err := s.quoteRepo.Create(ctx, quote)
+	if err != nil {
+		return false, fmt.Errorf("failed to save quote: %w", err)
+	}
+	if !created {
+		return false, nil
+	}
+
+	chainThreadID := chainThreadID(ctx, s.settingsService, quote.ChatID, quote.ThreadID)
+	if err := s.markovService.Quote(quote.ChatID, chainThreadID, quote.Text); err != nil {
+		return true, fmt.Errorf("failed to learn quote: %w", err)
+	}
+	// The chat chain learns the quotes of all topics
+	if chainThreadID != 0 {
+		if err := s.markovService.Quote(quote.ChatID, 0, quote.Text); err != nil {
+			return true, fmt.Errorf("failed to learn quote: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// Random returns a random quote of the book. While the book is empty, a
+// random message of the chat history is returned instead, without an
+// author name. It returns nil if there is neither.
+func (s *quoteService) Random(ctx context.Context, chatID int64) (*entity.Quote, error) {
+	quote, err := s.quoteRepo.GetRandom(ctx, chatID)
+	if err == nil {
+		return quote, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get random quote: %w", err)
+	}
+
+	message, err := s.messageRepo.GetRandom(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get random message: %w", err)
+	}
+
+	return &entity.Quote{
+		ChatID:    message.ChatID,
+		ThreadID:  message.ThreadID,
+		MessageID: message.MessageID,
+		UserID:    message.UserID,
+		Text:      message.Text,
+		SentAt:    message.CreatedAt,
+	}, nil
+}
+
+// Search returns the latest quotes containing the term
+func (s *quoteService) Search(ctx context.Context, chatID int64, term string, limit int) ([]*entity.Quote, error) {
+	quotes, err := s.quoteRepo.Search(ctx, chatID, term, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search quotes: %w", err)
+	}
+	return quotes, nil
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+	"go.uber.org/zap"
+)
+
+var _ adapters.Retention = (*retentionService)(nil)
+
+var retentionDeleted = expvar.NewInt("retention_deleted_total")
+
+type retentionService struct {
+	messageRepo     ports.MessageRepository
+	settingsRepo    ports.ChatSettingsRepository
+	settingsService adapters.Settings
+	botService      adapters.Bot
+	logger          *zap.Logger
+}
+
+func NewRetentionService(
+	messageRepo ports.MessageRepository,
+	settingsRepo ports.ChatSettingsRepository,
+	settingsSvc adapters.Settings,
+	botSvc adapters.Bot,
+	logger *zap.Logger,
+) adapters.Retention {
+	return &retentionService{
+		messageRepo:     messageRepo,
+		settingsRepo:    settingsRepo,
+		settingsService: settingsSvc,
+		botService:      botSvc,
+		logger:          logger.With(zap.String("service", "retention")),
+	}
+}
+
+// Sweep enforces the retention policies of all chats and returns the
+// number of deleted messages. A failing chat doesn't stop the sweep.
+func (s *retentionService) Sweep(ctx context.Context) (int64, error) {
+	chats, err := s.settingsRepo.GetWithRetention(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+
+	var total int64
+	for _, settings := range chats {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+
+		deleted, err := s.apply(ctx, settings)
+		total += deleted
+		if err != nil {
+			s.logger.Error("failed to apply retention policy", zap.Int64("chat_id", settings.ChatID), zap.Error(err))
+		}
+	}
+
+	return total, nil
+}
+
+// Apply enforces the retention policy of the chat right away
+func (s *retentionService) Apply(ctx context.Context, chatID int64) (int64, error) {
+	settings, err := s.settingsService.Get(ctx, chatID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.apply(ctx, settings)
+}
+
+// apply deletes the messages the policy doesn't keep and retrains the
+// chains of the chat, so that they don't produce deleted text
+func (s *retentionService) apply(ctx context.Context, settings *entity.ChatSettings) (int64, error) {
+	var deleted int64
+
+	if settings.RetentionDays > 0 {
+		before := time.Now().AddDate(0, 0, -settings.RetentionDays)
+		n, err := s.messageRepo.DeleteOlderThan(ctx, settings.ChatID, before)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete old messages: %w", err)
+		}
+		deleted += n
+	}
+
+	if settings.RetentionMessages > 0 {
+		n, err := s.messageRepo.DeleteBeyond(ctx, settings.ChatID, settings.RetentionMessages)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete excess messages: %w", err)
+		}
+		deleted += n
+	}
+
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	retentionDeleted.Add(deleted)
+	s.logger.Info("retention applied", zap.Int64("chat_id", settings.ChatID), zap.Int64("deleted", deleted))
+
+	if err := s.botService.Retrain(ctx, settings.ChatID); err != nil {
+		return deleted, fmt.Errorf("failed to retrain: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Rules = (*ruleService)(nil)
+
+var (
+	// ErrInvalidRule is returned when a rule can't be stored as given
+	ErrInvalidRule = errors.New("invalid rule")
+	// ErrTooManyRules is returned when the chat has as many rules as it is
+	// allowed to
+	ErrTooManyRules = errors.New("too many rules")
+)
+
+// compiledRule is a rule with its regular expression compiled once
+type compiledRule struct {
+	*entity.Rule
+	re *regexp.Regexp
+}
+
+type ruleService struct {
+	ruleRepo ports.RuleRepository
+	// maxRules bounds the rules of a chat, zero removes the limit
+	maxRules int
+
+	mu        sync.Mutex
+	cache     map[int64][]compiledRule
+	lastFired map[firedKey]time.Time
+}
+
+// firedKey identifies a rule firing in a chat, global rules cool down in
+// every chat on their own
+type firedKey struct {
+	chatID int64
+	ruleID int64
+}
+
+func NewRuleService(ruleRepo ports.RuleRepository, maxRules int) adapters.Rules {
+	return &ruleService{
+		ruleRepo:  ruleRepo,
+		maxRules:  maxRules,
+		cache:     make(map[int64][]compiledRule),
+		lastFired: make(map[firedKey]time.Time),
+	}
+}
+
+// Match returns the first chat or global rule matching the message that
+// passes its probability and cooldown checks, nil if none fired
+func (s *ruleService) Match(ctx context.Context, chatID int64, text string, kinds ...entity.RuleMatchType) (*entity.RuleMatch, error) {
+	chatRules, err := s.rules(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	globalRules, err := s.rules(ctx, entity.GlobalChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]compiledRule, 0, len(chatRules)+len(globalRules))
+	rules = append(rules, chatRules...)
+	rules = append(rules, globalRules...)
+
+	words := SplitWords(text)
+
+	for _, rule := range rules {
+		seed, ok := rule.match(text, words, kinds)
+		if !ok {
+			continue
+		}
+
+		if rule.Probability < 1 && rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		if !s.fire(chatID, rule.Rule) {
+			continue
+		}
+
+		return &entity.RuleMatch{Rule: rule.Rule, Seed: seed}, nil
+	}
+
+	return nil, nil
+}
+
+func (s *ruleService) AddRule(ctx context.Context, rule *entity.Rule) error {
+	if rule.ChatID == entity.GlobalChatID {
+		return fmt.Errorf("%w: global rules can't be added from a chat", ErrInvalidRule)
+	}
+
+	if _, err := compileRule(rule); err != nil {
+		return err
+	}
+
+	if s.maxRules > 0 {
+		count, err := s.ruleRepo.CountByChatID(ctx, rule.ChatID)
+		if err != nil {
+			return fmt.Errorf("failed to count rules: %w", err)
+		}
+		if count >= s.maxRules {
+			return ErrTooManyRules
+		}
+	}
+
+	if err := s.ruleRepo.Create(ctx, rule); err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+
+	s.invalidate(rule.ChatID)
+	return nil
+}
+
+func (s *ruleService) ListRules(ctx context.Context, chatID int64) ([]*entity.Rule, error) {
+	rules, err := s.ruleRepo.GetByChatID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (s *ruleService) DeleteRule(ctx context.Context, chatID, id int64) (bool, error) {
+	deleted, err := s.ruleRepo.Delete(ctx, chatID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rule: %w", err)
+	}
+
+	s.invalidate(chatID)
+	return deleted > 0, nil
+}
+
+// rules returns the compiled rules of the chat, loading them on first use
+func (s *ruleService) rules(ctx context.Context, chatID int64) ([]compiledRule, error) {
+	s.mu.Lock()
+	cached, exists := s.cache[chatID]
+	s.mu.Unlock()
+
+	if exists {
+		return cached, nil
+	}
+
+	rules, err := s.ruleRepo.GetByChatID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	s.mu.Lock()
+	s.cache[chatID] = compiled
+	s.mu.Unlock()
+
+	return compiled, nil
+}
+
+func (s *ruleService) invalidate(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cache, chatID)
+}
+
+// fire records the rule as fired in the chat unless it is still cooling
+// down there
+func (s *ruleService) fire(chatID int64, rule *entity.Rule) bool {
+	if rule.Cooldown == 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := firedKey{chatID: chatID, ruleID: rule.ID}
+	now := time.Now()
+	if last, exists := s.lastFired[key]; exists && now.Sub(last) < rule.Cooldown {
+		return false
+	}
+
+	s.lastFired[key] = now
+	return true
+}
+
+func compileRule(rule *entity.Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+
+	if rule.Probability <= 0 || rule.Probability > 1 {
+		return c, fmt.Errorf("%w: probability must be in (0, 1]", ErrInvalidRule)
+	}
+	if rule.Cooldown < 0 {
+		return c, fmt.Errorf("%w: cooldown can't be negative", ErrInvalidRule)
+	}
+
+	switch rule.MatchType {
+	case entity.RuleMatchRegex:
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return c, fmt.Errorf("%w: bad regex: %v", ErrInvalidRule, err)
+		}
+		c.re = re
+	case entity.RuleMatchWord:
+		if len(SplitWords(rule.Pattern)) != 1 {
+			return c, fmt.Errorf("%w: word pattern must be a single word", ErrInvalidRule)
+		}
+	case entity.RuleMatchVoice, entity.RuleMatchVideo, entity.RuleMatchSticker, entity.RuleMatchBoost:
+	default:
+		return c, fmt.Errorf("%w: unknown match type %q", ErrInvalidRule, rule.MatchType)
+	}
+
+	switch rule.ResponseType {
+	case entity.RuleResponseText, entity.RuleResponseSticker, entity.RuleResponseReaction:
+		if rule.Response == "" {
+			return c, fmt.Errorf("%w: response can't be empty", ErrInvalidRule)
+		}
+	case entity.RuleResponseGenerate:
+	default:
+		return c, fmt.Errorf("%w: unknown response type %q", ErrInvalidRule, rule.ResponseType)
+	}
+
+	return c, nil
+}
+
+// match reports whether the rule matches and returns the matched fragment
+func (r compiledRule) match(text string, words []string, kinds []entity.RuleMatchType) (string, bool) {
+	switch r.MatchType {
+	case entity.RuleMatchRegex:
+		if text == "" {
+			return "", false
+		}
+		loc := r.re.FindStringIndex(text)
+		if loc == nil {
+			return "", false
+		}
+		return text[loc[0]:loc[1]], true
+	case entity.RuleMatchWord:
+		for _, word := range words {
+			if strings.EqualFold(word, r.Pattern) {
+				return word, true
+			}
+		}
+		return "", false
+	default:
+		for _, kind := range kinds {
+			if kind == r.MatchType {
+				return text, true
+			}
+		}
+		return "", false
+	}
+}
+
+// SplitWords splits text into words ignoring surrounding punctuation, rules
+// and wake words match on them
+func SplitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/scheduler"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Schedules = (*scheduleService)(nil)
+
+// ErrInvalidSchedule is returned when a schedule can't be stored as given
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+type scheduleService struct {
+	scheduleRepo ports.ScheduleRepository
+	loc          *time.Location
+}
+
+func NewScheduleService(scheduleRepo ports.ScheduleRepository, loc *time.Location) adapters.Schedules {
+	return &scheduleService{
+		scheduleRepo: scheduleRepo,
+		loc:          loc,
+	}
+}
+
+func (s *scheduleService) List(ctx context.Context, chatID int64) ([]*entity.Schedule, error) {
+	schedules, err := s.scheduleRepo.GetByChatID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// Set validates the schedule and stores it, replacing the schedule of the
+// same job in the chat
+func (s *scheduleService) Set(ctx context.Context, schedule *entity.Schedule) error {
+	switch schedule.Job {
+	case entity.JobQuote, entity.JobDigest, entity.JobRetrain, entity.JobRetention:
+		if schedule.Arg != "" {
+			return fmt.Errorf("%w: job %q takes no argument", ErrInvalidSchedule, schedule.Job)
+		}
+	default:
+		return fmt.Errorf("%w: unknown job %q", ErrInvalidSchedule, schedule.Job)
+	}
+
+	spec, err := scheduler.ParseSpec(schedule.Spec, s.loc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	schedule.NextRunAt = spec.Next(time.Now())
+
+	if err := s.scheduleRepo.Save(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	return nil
+}
+
+func (s *scheduleService) Delete(ctx context.Context, chatID int64, job entity.JobKind) (bool, error) {
+	deleted, err := s.scheduleRepo.Delete(ctx, chatID, job)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete schedule: %w", err)
+	}
+
+	return deleted > 0, nil
+}
@@ -17,13 +17,42 @@ type Params struct {
 }
 
 type Service struct {
-	BotService adapters.Bot
+	BotService       adapters.Bot
+	RuleService      adapters.Rules
+	SettingsService  adapters.Settings
+	ChartService     adapters.Charts
+	RetentionService adapters.Retention
+	IngestService    adapters.Ingest
+	FeedbackService  adapters.Feedback
+	QuoteService     adapters.Quotes
+	// ScheduleService is nil if the scheduler is disabled
+	ScheduleService adapters.Schedules
+	// TranscriptionService is nil if transcription is disabled
+	TranscriptionService adapters.Transcription
 }
 
-func NewService(params Params) *Service {
+func NewService(params Params) (*Service, error) {
 	f := NewServiceFactory(params)
+	settings := f.NewSettingsService()
+	markov := f.NewMarkovService()
+	ingest := f.NewIngestService(markov)
+	bot := f.NewBotService(settings, markov, ingest)
 
-	return &Service{
-		BotService: f.NewBotService(),
+	schedules, err := f.NewScheduleService()
+	if err != nil {
+		return nil, err
 	}
+
+	return &Service{
+		BotService:           bot,
+		RuleService:          f.NewRuleService(),
+		SettingsService:      settings,
+		ChartService:         f.NewChartService(),
+		RetentionService:     f.NewRetentionService(settings, bot),
+		IngestService:        ingest,
+		FeedbackService:      f.NewFeedbackService(markov, settings),
+		QuoteService:         f.NewQuoteService(markov, settings),
+		ScheduleService:      schedules,
+		TranscriptionService: f.NewTranscriptionService(bot),
+	}, nil
 }
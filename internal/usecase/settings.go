@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+var _ adapters.Settings = (*settingsService)(nil)
+
+type settingsService struct {
+	settingsRepo ports.ChatSettingsRepository
+
+	mu    sync.Mutex
+	cache map[int64]entity.ChatSettings
+}
+
+func NewSettingsService(settingsRepo ports.ChatSettingsRepository) adapters.Settings {
+	return &settingsService{
+		settingsRepo: settingsRepo,
+		cache:        make(map[int64]entity.ChatSettings),
+	}
+}
+
+// Get returns a copy of the chat settings, callers may modify it freely
+func (s *settingsService) Get(ctx context.Context, chatID int64) (*entity.ChatSettings, error) {
+	s.mu.Lock()
+	cached, exists := s.cache[chatID]
+	s.mu.Unlock()
+
+	if exists {
+		return &cached, nil
+	}
+
+	settings, err := s.settingsRepo.Get(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat settings: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[chatID] = *settings
+	s.mu.Unlock()
+
+	return settings, nil
+}
+
+// Update applies fn to the chat settings and stores the result
+func (s *settingsService) Update(ctx context.Context, chatID int64, fn func(settings *entity.ChatSettings)) error {
+	settings, err := s.Get(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	fn(settings)
+
+	if err := s.settingsRepo.Save(ctx, settings); err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[chatID] = *settings
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+)
+
+const (
+	statsTopWords    = 10
+	statsTopUsers    = 5
+	statsTopSets     = 5
+	statsMinWordSize = 3
+)
+
+// stopWords are frequent words carrying no meaning, excluded from top words
+var stopWords = []string{
+	// Russian
+	"это", "что", "как", "так", "вот", "она", "они", "оно", "его", "её", "мне", "меня",
+	"тебя", "тебе", "нас", "вас", "них", "все", "всё", "ещё", "еще", "уже", "там", "тут",
+	"где", "когда", "если", "или", "для", "без", "под", "над", "про", "при", "чем", "чём",
+	"был", "была", "было", "были", "быть", "есть", "нет", "только", "тоже", "также",
+	"потому", "чтобы", "этот", "эта", "эти", "того", "тот", "той", "кто", "ним",
+	"него", "неё", "нее", "мой", "моя", "твой", "сам", "очень", "может", "можно", "надо",
+	"нужно", "просто", "вообще", "даже", "ага", "ладно", "короче",
+	// English
+	"the", "and", "you", "that", "this", "with", "for", "are", "was", "but", "not",
+	"have", "has", "had", "what", "just", "all", "can", "its", "it's", "your", "they",
+	"from", "about", "there", "would", "will", "been", "one", "out", "get",
+}
+
+// GetChatStats collects the statistics of the chat over the period
+func (s *botService) GetChatStats(ctx context.Context, chatID int64, period entity.StatsPeriod) (*entity.ChatStats, error) {
+	since := period.Since(time.Now())
+
+	stats := &entity.ChatStats{Period: period}
+
+	var err error
+
+	stats.MessageCount, err = s.statsRepo.CountMessages(ctx, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message count: %w", err)
+	}
+
+	stats.StickerCount, err = s.statsRepo.CountStickers(ctx, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker count: %w", err)
+	}
+
+	stats.TopWords, err = s.statsRepo.TopWords(ctx, chatID, since, stopWords, statsMinWordSize, statsTopWords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top words: %w", err)
+	}
+
+	stats.TopUsers, err = s.statsRepo.TopUsers(ctx, chatID, since, statsTopUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top users: %w", err)
+	}
+
+	stats.Daily, err = s.statsRepo.Daily(ctx, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily activity: %w", err)
+	}
+
+	stats.Hourly, err = s.statsRepo.Hourly(ctx, chatID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hourly activity: %w", err)
+	}
+
+	stats.StickerSets, err = s.statsRepo.StickerSets(ctx, chatID, since, statsTopSets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sticker sets: %w", err)
+	}
+
+	chain := s.markovService.GetChainStats(chatID)
+	stats.Vocabulary = chain.Vocabulary
+	stats.States = chain.States
+
+	return stats, nil
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+	"go.uber.org/zap"
+)
+
+var _ adapters.Transcription = (*transcriptionService)(nil)
+
+var transcriptions = expvar.NewMap("transcriptions_total")
+
+// transcriptionService transcribes voice messages in a pool of workers and
+// stores the results as regular messages so that they feed the chain
+type transcriptionService struct {
+	transcriber ports.Transcriber
+	botService  adapters.Bot
+	jobs        chan adapters.VoiceJob
+	timeout     time.Duration
+	logger      *zap.Logger
+
+	// mu guards closed, Enqueue holds it for reading so that the queue is
+	// never closed under it
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+func NewTranscriptionService(
+	transcriber ports.Transcriber,
+	botSvc adapters.Bot,
+	workers, queueSize int,
+	timeout time.Duration,
+	logger *zap.Logger,
+) adapters.Transcription {
+	svc := &transcriptionService{
+		transcriber: transcriber,
+		botService:  botSvc,
+		jobs:        make(chan adapters.VoiceJob, queueSize),
+		timeout:     timeout,
+		logger:      logger.With(zap.String("service", "transcription")),
+	}
+
+	for i := 0; i < workers; i++ {
+		svc.workers.Add(1)
+		go svc.worker()
+	}
+
+	return svc
+}
+
+// Enqueue schedules the job, false is returned if the queue is full or
+// draining started
+func (s *transcriptionService) Enqueue(job adapters.VoiceJob) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		transcriptions.Add("dropped", 1)
+		return false
+	}
+
+	select {
+	case s.jobs <- job:
+		transcriptions.Add("queued", 1)
+		return true
+	default:
+		transcriptions.Add("dropped", 1)
+		return false
+	}
+}
+
+// Drain stops accepting jobs and waits until the queued ones are
+// transcribed, the jobs left are reported if the context is done first
+func (s *transcriptionService) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.jobs)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d voice messages left untranscribed: %w", len(s.jobs), ctx.Err())
+	}
+}
+
+func (s *transcriptionService) worker() {
+	defer s.workers.Done()
+
+	for job := range s.jobs {
+		if err := s.process(job); err != nil {
+			transcriptions.Add("failed", 1)
+			s.logger.Error("failed to transcribe voice message",
+				zap.Int64("chat_id", job.Message.ChatID),
+				zap.Int64("message_id", job.Message.MessageID),
+				zap.Error(err),
+			)
+			continue
+		}
+		transcriptions.Add("done", 1)
+	}
+}
+
+func (s *transcriptionService) process(job adapters.VoiceJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	audio, err := job.Downloader.Download(ctx, job.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to download voice message: %w", err)
+	}
+	defer audio.Close()
+
+	language := s.botService.ChatLanguage(ctx, job.Message.ChatID)
+	text, err := s.transcriber.Transcribe(ctx, audio, language)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		return nil
+	}
+
+	job.Message.Text = text
+	job.Message.Source = entity.MessageSourceVoice
+
+	return s.botService.HandleMessage(ctx, job.Message)
+}
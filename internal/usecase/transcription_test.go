@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/transcriber"
+	"github.com/malinatrash/egonez/internal/usecase/adapters"
+)
+
+// fakeBot records the messages it is given
+type fakeBot struct {
+	adapters.Bot
+
+	mu       sync.Mutex
+	messages []*entity.Message
+}
+
+func (b *fakeBot) HandleMessage(ctx context.Context, message *entity.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, message)
+	return nil
+}
+
+func (b *fakeBot) ChatLanguage(ctx context.Context, chatID int64) string {
+	return "en"
+}
+
+// fakeDownloader serves the same audio for every file
+type fakeDownloader struct{}
+
+func (fakeDownloader) Download(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("audio")), nil
+}
+
+func voiceJob(messageID int64) adapters.VoiceJob {
+	return adapters.VoiceJob{
+		Message:    &entity.Message{ChatID: 1, MessageID: messageID, UserID: 2},
+		FileID:     "voice",
+		Downloader: fakeDownloader{},
+	}
+}
+
+func TestTranscription(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "stored", text: "hello there", want: 1},
+		{name: "empty text skipped", text: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := &fakeBot{}
+			svc := NewTranscriptionService(&transcriber.Stub{Text: tt.text}, bot, 2, 10, time.Second, zap.NewNop())
+
+			if !svc.Enqueue(voiceJob(10)) {
+				t.Fatal("Enqueue refused the job")
+			}
+			if err := svc.Drain(context.Background()); err != nil {
+				t.Fatalf("Drain: %v", err)
+			}
+
+			if len(bot.messages) != tt.want {
+				t.Fatalf("stored %d messages, want %d", len(bot.messages), tt.want)
+			}
+			if tt.want == 0 {
+				return
+			}
+			if msg := bot.messages[0]; msg.Text != tt.text || msg.Source != entity.MessageSourceVoice {
+				t.Errorf("stored %q from %q, want %q from %q", msg.Text, msg.Source, tt.text, entity.MessageSourceVoice)
+			}
+		})
+	}
+}
+
+func TestTranscriptionDrain(t *testing.T) {
+	bot := &fakeBot{}
+	svc := NewTranscriptionService(&transcriber.Stub{Text: "hello"}, bot, 1, 10, time.Second, zap.NewNop())
+
+	for i := range 5 {
+		if !svc.Enqueue(voiceJob(int64(i + 1))) {
+			t.Fatalf("Enqueue refused job %d", i)
+		}
+	}
+	if err := svc.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+
+	if len(bot.messages) != 5 {
+		t.Errorf("stored %d messages, want all 5 queued", len(bot.messages))
+	}
+	if svc.Enqueue(voiceJob(6)) {
+		t.Error("Enqueue accepted a job after Drain")
+	}
+}
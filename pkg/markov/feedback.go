@@ -0,0 +1,134 @@
+package markov
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+
+	"github.com/mb-14/gomarkov"
+	"go.uber.org/zap"
+)
+
+const (
+	// feedbackStep is the change of the weight of a transition per point
+	// of score users gave the texts it was used in
+	feedbackStep = 0.25
+	// minWeight and maxWeight bound the weight of a transition, so that no
+	// amount of votes can remove a transition or force it
+	minWeight = 0.25
+	maxWeight = 2.0
+	// maxTextScore bounds the score a single text counts with, so that a
+	// text voted for by many users can't outweigh all the others
+	maxTextScore = 3
+	// maxRedraws bounds the draws made to honor the weights of a state
+	maxRedraws = 8
+	// maxRated is the number of the latest rated generations applied to a
+	// chain when it is loaded
+	maxRated = 500
+)
+
+// feedback holds the score of the transitions of a chain, by the state and
+// the next token
+type feedback map[string]map[string]float64
+
+func stateKey(state []string) string {
+	return strings.Join(state, "\x00")
+}
+
+// add adds the score to every transition of the tokens
+func (f feedback) add(tokens []string, order int, score float64) {
+	for i := 0; i+order < len(tokens); i++ {
+		key := stateKey(tokens[i : i+order])
+		next := tokens[i+order]
+
+		scores, exists := f[key]
+		if !exists {
+			scores = make(map[string]float64)
+			f[key] = scores
+		}
+
+		scores[next] += score
+		if scores[next] == 0 {
+			delete(scores, next)
+		}
+		if len(scores) == 0 {
+			delete(f, key)
+		}
+	}
+}
+
+// weight returns the weight of the transition, one if it isn't rated
+func (f feedback) weight(state []string, next string) float64 {
+	weight := 1 + feedbackStep*f[stateKey(state)][next]
+	return min(max(weight, minWeight), maxWeight)
+}
+
+// textScore returns the score a text counts with
+func textScore(score int) float64 {
+	return float64(min(max(score, -maxTextScore), maxTextScore))
+}
+
+// Feedback moves the transitions a generated text consists of by the change
+// of the score users gave it from before to after, a negative score
+// penalizes them. Chains not in memory are left alone, they get the score
+// once they are loaded from the database.
+func (s *Service) Feedback(chatID, threadID int64, text string, before, after int) {
+	key := chainKey{ChatID: chatID, ThreadID: threadID}
+	score := textScore(after) - textScore(before)
+
+	s.mu.Lock()
+	e, exists := s.chains.peek(key)
+	s.mu.Unlock()
+
+	if !exists || score == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.feedback == nil {
+		e.feedback = make(feedback)
+	}
+	e.feedback.add(strings.Fields(text), e.chain.Order, score)
+}
+
+// loadFeedback returns the scores of the rated generations of the chain
+func (s *Service) loadFeedback(ctx context.Context, key chainKey, order int) feedback {
+	if s.generations == nil {
+		return nil
+	}
+
+	rated, err := s.generations.GetRated(ctx, key.ChatID, key.ThreadID, maxRated)
+	if err != nil {
+		s.logg.Warn("failed to load feedback",
+			zap.Int64("chat_id", key.ChatID),
+			zap.Int64("thread_id", key.ThreadID),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	f := make(feedback)
+	for _, generation := range rated {
+		f.add(strings.Fields(generation.Text), order, textScore(generation.Score()))
+	}
+	return f
+}
+
+// nextToken draws the token following the state. A drawn token is redrawn
+// with a chance falling with the weight of its transition, which makes the
+// chance to pick it proportional to its frequency times its weight.
+func nextToken(chain *gomarkov.Chain, f feedback, state []string) (string, error) {
+	next, err := chain.GenerateDeterministic(state, prng)
+	if err != nil || len(f[stateKey(state)]) == 0 {
+		return next, err
+	}
+
+	for i := 0; i < maxRedraws && rand.Float64()*maxWeight >= f.weight(state, next); i++ {
+		if next, err = chain.GenerateDeterministic(state, prng); err != nil {
+			return "", err
+		}
+	}
+	return next, nil
+}
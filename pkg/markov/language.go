@@ -0,0 +1,117 @@
+package markov
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages a chat can be set to, an empty language is detected from the
+// messages of the chat
+var Languages = []string{"ru", "uk", "kk", "en"}
+
+// scripts lists the alphabets the words of each language are written in
+var scripts = map[string][]*unicode.RangeTable{
+	"ru": {unicode.Cyrillic},
+	"uk": {unicode.Cyrillic},
+	"kk": {unicode.Cyrillic},
+	"en": {unicode.Latin},
+}
+
+const (
+	// kazakhLetters and ukrainianLetters are only used by these languages
+	// among the Cyrillic ones the bot knows, "і" is shared by both
+	kazakhLetters    = "әғқңөұүһӘҒҚҢӨҰҮҺ"
+	ukrainianLetters = "їєґЇЄҐ"
+
+	// minScriptShare is the share of letters a script needs to be allowed
+	// in a chat of mixed scripts
+	minScriptShare = 0.2
+	// minLetterShare is the share of letters specific to a language the
+	// messages need to be taken for it
+	minLetterShare = 0.005
+)
+
+// IsLanguage reports whether the bot knows the language
+func IsLanguage(lang string) bool {
+	_, exists := scripts[lang]
+	return exists
+}
+
+// detection is the language of a corpus and the scripts used in it
+type detection struct {
+	language string
+	scripts  []*unicode.RangeTable
+}
+
+// detect guesses the language of the messages by the letters used in them.
+// Every script making up a noticeable part of the letters is allowed, so
+// that chats mixing languages keep all of them.
+func detect(texts []string) detection {
+	var letters, cyrillic, latin, kazakh, ukrainian int
+	for _, text := range texts {
+		for _, r := range text {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			letters++
+			switch {
+			case unicode.Is(unicode.Cyrillic, r):
+				cyrillic++
+				if strings.ContainsRune(kazakhLetters, r) {
+					kazakh++
+				} else if strings.ContainsRune(ukrainianLetters, r) {
+					ukrainian++
+				}
+			case unicode.Is(unicode.Latin, r):
+				latin++
+			}
+		}
+	}
+
+	if letters == 0 {
+		return detection{}
+	}
+
+	var d detection
+	if float64(cyrillic) >= minScriptShare*float64(letters) {
+		d.scripts = append(d.scripts, unicode.Cyrillic)
+	}
+	if float64(latin) >= minScriptShare*float64(letters) {
+		d.scripts = append(d.scripts, unicode.Latin)
+	}
+
+	switch {
+	case latin > cyrillic:
+		d.language = "en"
+	case float64(kazakh) >= minLetterShare*float64(cyrillic):
+		d.language = "kk"
+	case float64(ukrainian) >= minLetterShare*float64(cyrillic):
+		d.language = "uk"
+	case cyrillic > 0:
+		d.language = "ru"
+	}
+
+	return d
+}
+
+// isWord reports whether the token may appear in generated text: it has a
+// letter of one of the scripts, or no letters at all like numbers and
+// emoji. Any letter is fine when no scripts are given.
+func isWord(token string, scripts []*unicode.RangeTable) bool {
+	if len(scripts) == 0 {
+		return true
+	}
+
+	hasLetters := false
+	for _, r := range token {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.IsOneOf(scripts, r) {
+			return true
+		}
+		hasLetters = true
+	}
+
+	return !hasLetters
+}
@@ -0,0 +1,113 @@
+package markov
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/mb-14/gomarkov"
+)
+
+// entry is a chain kept in memory together with the messages used as
+// context for generation. The chain is guarded by mu, everything else by
+// the lock of the Service.
+type entry struct {
+	key   chainKey
+	mu    sync.RWMutex
+	chain *gomarkov.Chain
+	// recent is never appended to in place, it is replaced by a new slice
+	// so that readers may keep the old one without copying
+	recent []string
+	// tokens is the number of tokens the chain was trained on, used as an
+	// estimate of its size
+	tokens int
+	// detected is the language of the messages the chain was loaded from
+	detected detection
+	// feedback holds the scores users gave the chain's transitions, it is
+	// guarded by mu like the chain
+	feedback feedback
+}
+
+// lru keeps chains ordered from the most to the least recently used
+type lru struct {
+	ll     *list.List
+	items  map[chainKey]*list.Element
+	tokens int
+}
+
+func newLRU() *lru {
+	return &lru{
+		ll:    list.New(),
+		items: make(map[chainKey]*list.Element),
+	}
+}
+
+// get returns the entry and marks it as used
+func (c *lru) get(key chainKey) (*entry, bool) {
+	el, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return el.Value.(*entry), true
+}
+
+// peek returns the entry without marking it as used
+func (c *lru) peek(key chainKey) (*entry, bool) {
+	el, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	return el.Value.(*entry), true
+}
+
+// put adds the entry as the most recently used one, replacing the entry
+// of the same key
+func (c *lru) put(e *entry) {
+	c.remove(e.key)
+	c.items[e.key] = c.ll.PushFront(e)
+	c.tokens += e.tokens
+}
+
+func (c *lru) remove(key chainKey) {
+	el, exists := c.items[key]
+	if !exists {
+		return
+	}
+
+	c.ll.Remove(el)
+	delete(c.items, key)
+	c.tokens -= el.Value.(*entry).tokens
+}
+
+// grow accounts tokens trained into an entry already in the cache
+func (c *lru) grow(e *entry, tokens int) {
+	e.tokens += tokens
+	c.tokens += tokens
+}
+
+// keys returns the keys of all entries
+func (c *lru) keys() []chainKey {
+	keys := make([]chainKey, 0, len(c.items))
+	for key := range c.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// evict drops the least recently used entries until at most maxChains are
+// left holding at most maxTokens, zero meaning no limit. The most recently
+// used entry is always kept. It returns the number of evicted entries.
+func (c *lru) evict(maxChains, maxTokens int) int {
+	evicted := 0
+	for c.ll.Len() > 1 &&
+		((maxChains > 0 && c.ll.Len() > maxChains) || (maxTokens > 0 && c.tokens > maxTokens)) {
+		c.remove(c.ll.Back().Value.(*entry).key)
+		evicted++
+	}
+	return evicted
+}
+
+func (c *lru) len() int {
+	return c.ll.Len()
+}
@@ -2,10 +2,17 @@ package markov
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
-	"reflect"
+	"math/rand"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/mb-14/gomarkov"
 	"go.uber.org/zap"
@@ -16,67 +23,203 @@ import (
 
 // ChainStats holds statistics about a Markov chain
 type ChainStats struct {
-	Order        int
-	TotalTokens  int
-	UniqueNGrams int
+	Order int
+	// States is the number of states with known transitions
+	States int
+	// Vocabulary is the number of distinct tokens the chain can produce
+	Vocabulary int
+	// Transitions is the total number of transitions learnt
+	Transitions int
 }
 
+// chainJSON mirrors the JSON encoding of gomarkov.Chain, the only way to
+// look into its frequency matrix
+type chainJSON struct {
+	Order   int                 `json:"int"`
+	FreqMat map[int]map[int]int `json:"freq_mat"`
+}
+
+// chainKey identifies a chain, ThreadID is zero for the chain of a whole chat
+type chainKey struct {
+	ChatID   int64
+	ThreadID int64
+}
+
+// Options configure the Service
+type Options struct {
+	// Order is the number of words a chain state consists of
+	Order int
+	// MaxChains and MaxTokens bound the chains kept in memory, the least
+	// recently used ones are evicted and reloaded from the database when
+	// needed again. Zero means no limit.
+	MaxChains int
+	MaxTokens int
+	// WarmUpChats is the number of the most active chats loaded on start
+	// by WarmUpWorkers in parallel
+	WarmUpChats   int
+	WarmUpWorkers int
+	// Retries is the number of times a load failing with a transient
+	// database error is retried, waiting RetryBackoff doubled every time
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+const (
+	// quoteWeight is the number of times a quote is learnt, as many as a
+	// recent message and one more
+	quoteWeight = 3
+	// maxQuotes is the number of the latest quotes learnt when a chain is
+	// loaded
+	maxQuotes = 1000
+	// maxStartDraws bounds the draws made for a start word of the language
+	maxStartDraws = 32
+)
+
+var (
+	// ErrNoData is returned when there is nothing to generate from
+	ErrNoData = errors.New("no data available for generation")
+	// ErrWarmingUp is returned for chats not loaded yet while warming up
+	ErrWarmingUp = errors.New("still warming up")
+)
+
+var (
+	chainHits      = expvar.NewInt("markov_chain_hits_total")
+	chainMisses    = expvar.NewInt("markov_chain_misses_total")
+	chainEvictions = expvar.NewInt("markov_chain_evictions_total")
+	chainsLoaded   = expvar.NewInt("markov_chains_loaded")
+	chainTokens    = expvar.NewInt("markov_chain_tokens")
+)
+
+// Service keeps a Markov chain per chat and per topic.
+//
+// Concurrency: mu guards the LRU and the bookkeeping of its entries, it is
+// only held for map and list operations. Each chain has a lock of its own,
+// so that training one chat never blocks generation in another: Train
+// takes it for writing and Generate for reading. Load builds a new chain
+// without any lock and swaps it in under mu, generations already running
+// keep using the old one. Concurrent loads of the same chain wait for the
+// first one instead of training it again.
 type Service struct {
-	order  int
-	chains map[int64]*gomarkov.Chain
-	mu     sync.RWMutex
-	repo   ports.MessageRepository
+	opts Options
+	// chains holds the chains in memory, recent messages of a chain are
+	// kept with it as context (last 20 messages per chain)
+	chains *lru
+	// loading holds the chains being loaded
+	loading map[chainKey]*pendingLoad
+	mu      sync.Mutex
+	repo    ports.MessageRepository
+	// generations holds the texts users rated, nil if ratings aren't used
+	generations ports.GenerationRepository
+	// quotes holds the quote books, nil if quotes aren't learnt
+	quotes ports.QuoteRepository
 	logg   *zap.Logger
-	// Recent messages for context (last 20 messages per chat)
-	recentMessages map[int64][]string
+	// ready is set once the warm-up is over
+	ready atomic.Bool
 }
 
-func NewService(order int, repo ports.MessageRepository, logg *zap.Logger) *Service {
+func NewService(opts Options, repo ports.MessageRepository, generations ports.GenerationRepository, quotes ports.QuoteRepository, logg *zap.Logger) *Service {
 	svc := &Service{
-		order:          order,
-		chains:         make(map[int64]*gomarkov.Chain),
-		repo:           repo,
-		logg:           logg.With(zap.String("service", "markov")),
-		recentMessages: make(map[int64][]string),
-	}
-
-	// Load all chats in background
-	go func() {
-		ctx := context.Background()
-		if err := svc.LoadAllChats(ctx); err != nil {
-			svc.logg.Error("failed to load all chats", zap.Error(err))
-		} else {
-			svc.logg.Info("successfully loaded all chats")
-		}
-	}()
+		opts:        opts,
+		chains:      newLRU(),
+		loading:     make(map[chainKey]*pendingLoad),
+		repo:        repo,
+		generations: generations,
+		quotes:      quotes,
+		logg:        logg.With(zap.String("service", "markov")),
+	}
+
+	if opts.WarmUpChats <= 0 {
+		svc.setReady()
+	} else {
+		// Warm up the most active chats in background
+		go func() {
+			ctx := context.Background()
+			if err := svc.WarmUp(ctx, opts.WarmUpChats); err != nil {
+				svc.logg.Error("failed to warm up chats", zap.Error(err))
+			} else {
+				svc.logg.Info("successfully warmed up chats")
+			}
+		}()
+	}
 
 	return svc
 }
 
-func (s *Service) Train(chatID int64, text string) error {
-	chain := s.getOrCreateChain(chatID)
+// Train trains the chain on a new message. Chains not in memory are left
+// alone, they see the message once they are loaded from the database.
+func (s *Service) Train(chatID, threadID int64, text string) error {
+	return s.train(chainKey{ChatID: chatID, ThreadID: threadID}, text, 1)
+}
+
+// Quote trains the chain on a message saved to the quote book, it weighs
+// more than an ordinary message
+func (s *Service) Quote(chatID, threadID int64, text string) error {
+	return s.train(chainKey{ChatID: chatID, ThreadID: threadID}, text, quoteWeight)
+}
+
+func (s *Service) train(key chainKey, text string, weight float64) error {
 	tokens := strings.Fields(text)
+
+	s.mu.Lock()
+	e, exists := s.chains.peek(key)
+	var prev string
+	if exists && len(e.recent) > 0 {
+		prev = e.recent[len(e.recent)-1]
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	if isMediaMessage(tokens) {
+		tokens = mediaTokens(prev, tokens[0], s.opts.Order)
+	}
 	if len(tokens) < 2 {
 		return nil
 	}
 
-	// Add to chain with weight based on recency
-	trainWithWeight(chain, tokens, 1.0)
+	e.mu.Lock()
+	trainWithWeight(e.chain, tokens, weight)
+	e.mu.Unlock()
 
-	// Update recent messages
 	s.mu.Lock()
-	if _, exists := s.recentMessages[chatID]; !exists {
-		s.recentMessages[chatID] = make([]string, 0, 20)
-	}
-	s.recentMessages[chatID] = append(s.recentMessages[chatID], text)
-	if len(s.recentMessages[chatID]) > 20 {
-		s.recentMessages[chatID] = s.recentMessages[chatID][1:]
+	defer s.mu.Unlock()
+
+	// The chain may have been evicted or replaced meanwhile, a replaced
+	// one was loaded from the database and has seen the message already
+	if cur, exists := s.chains.peek(key); !exists || cur != e {
+		return nil
 	}
-	s.mu.Unlock()
 
+	s.chains.grow(e, len(tokens))
+
+	// Update recent messages
+	recent := make([]string, 0, 20)
+	recent = append(recent, e.recent[max(len(e.recent)-19, 0):]...)
+	e.recent = append(recent, text)
+
+	s.evict()
 	return nil
 }
 
+// isMediaMessage reports whether the message is a sticker or an animation
+// sent on its own
+func isMediaMessage(tokens []string) bool {
+	return len(tokens) == 1 && entity.IsMediaToken(tokens[0])
+}
+
+// mediaTokens returns the tokens to train a media message on. Media has no
+// words of its own, so it is learnt as following the end of the previous
+// message
+func mediaTokens(prev, media string, order int) []string {
+	tokens := strings.Fields(prev)
+	if len(tokens) > order {
+		tokens = tokens[len(tokens)-order:]
+	}
+	return append(tokens, media)
+}
+
 func trainWithWeight(chain *gomarkov.Chain, tokens []string, weight float64) {
 	for i := 0; i < len(tokens)-chain.Order; i++ {
 		state := make([]string, chain.Order)
@@ -90,43 +233,67 @@ func trainWithWeight(chain *gomarkov.Chain, tokens []string, weight float64) {
 	}
 }
 
-func (s *Service) Generate(chatID int64, prefix string, maxLength int) (string, error) {
+// Generate continues the prefix with words of the language, or of the
+// language detected in the chat if it is empty
+func (s *Service) Generate(chatID, threadID int64, prefix string, maxLength int, lang string) (string, error) {
+	key := chainKey{ChatID: chatID, ThreadID: threadID}
+
 	// Log generation attempt
 	s.logg.Debug("generating text",
 		zap.Int64("chat_id", chatID),
+		zap.Int64("thread_id", threadID),
 		zap.String("prefix", prefix),
 		zap.Int("max_length", maxLength),
 	)
 
-	// Get chain and check if it exists
-	chain := s.getChain(chatID)
-	if chain == nil {
-		return "", fmt.Errorf("no data available for generation")
+	// Get chain and check if it exists, together with recent messages for
+	// better context
+	s.mu.Lock()
+	e, exists := s.chains.get(key)
+	var recentContext []string
+	if exists {
+		recentContext = e.recent
 	}
+	s.mu.Unlock()
 
-	// Try to use recent messages for better context
-	s.mu.RLock()
-	recentContext := make([]string, 0, 20)
-	if msgs, exists := s.recentMessages[chatID]; exists {
-		recentContext = append(recentContext, msgs...)
+	if !exists {
+		return "", ErrNoData
 	}
-	s.mu.RUnlock()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	chain := e.chain
+
+	allowed := e.detected.scripts
+	if lang != "" {
+		allowed = scripts[lang]
+	}
+
+	// Process the prefix, words of other languages are left out so that
+	// the text doesn't start in one
+	tokens := filterWords(strings.Fields(prefix), allowed)
 
 	// If no prefix provided, try to use recent messages as context
 	if prefix == "" && len(recentContext) > 0 {
 		// Take last few words from recent messages
 		recentText := strings.Join(recentContext, " ")
-		tokens := strings.Fields(recentText)
+		tokens = filterWords(strings.Fields(recentText), allowed)
 		if len(tokens) > 5 { // Take last 5 words as context
 			tokens = tokens[len(tokens)-5:]
 		}
-		prefix = strings.Join(tokens, " ")
 	}
 
-	// Process the prefix
-	tokens := strings.Fields(prefix)
+	// Start from a word of the prefix the chain knows, so that the answer
+	// continues it instead of echoing the whole prefix back
+	if state := seedState(chain, tokens); state != nil {
+		tokens = state
+	}
+
 	if len(tokens) == 0 {
-		token, err := s.getRandomToken(chatID)
+		token, err := s.getRandomToken(chain, allowed)
+		if errors.Is(err, ErrNoData) {
+			return "", err
+		}
 		if err != nil {
 			return "", fmt.Errorf("failed to get random token: %w", err)
 		}
@@ -135,7 +302,7 @@ func (s *Service) Generate(chatID int64, prefix string, maxLength int) (string,
 
 	// Ensure we have enough tokens for the chain order
 	for len(tokens) < chain.Order {
-		token, err := s.getRandomToken(chatID)
+		token, err := s.getRandomToken(chain, allowed)
 		if err != nil {
 			break
 		}
@@ -144,15 +311,19 @@ func (s *Service) Generate(chatID int64, prefix string, maxLength int) (string,
 
 	// Prepare the result slice with the initial tokens
 	var result strings.Builder
-	for i, token := range tokens {
-		if i > 0 && !isPunctuation(token) {
+	wordCount := 0
+	for _, token := range tokens {
+		if token == gomarkov.StartToken {
+			continue
+		}
+		if result.Len() > 0 && !isPunctuation(token) {
 			result.WriteString(" ")
 		}
 		result.WriteString(token)
+		wordCount++
 	}
 
 	// Track sentence state
-	wordCount := len(tokens)
 	const maxSentenceLength = 12 // Shorter sentences for better readability
 
 	for i := 0; i < maxLength; i++ {
@@ -163,14 +334,14 @@ func (s *Service) Generate(chatID int64, prefix string, maxLength int) (string,
 		}
 
 		// Generate next token
-		next, err := chain.Generate(currentTokens)
+		next, err := nextToken(chain, e.feedback, currentTokens)
 		if err != nil || next == "" || next == gomarkov.EndToken {
 			break
 		}
 
 		// Skip empty or invalid tokens
 		next = strings.TrimSpace(next)
-		if next == "" || (!isRussianWord(next) && !isPunctuation(next)) {
+		if next == "" || (!isWord(next, allowed) && !isPunctuation(next) && !entity.IsMediaToken(next)) {
 			continue
 		}
 
@@ -214,17 +385,6 @@ func (s *Service) Generate(chatID int64, prefix string, maxLength int) (string,
 	return "", nil
 }
 
-// isRussianWord checks if the word contains Russian characters
-func isRussianWord(word string) bool {
-	for _, r := range word {
-		// Russian Unicode range
-		if (r >= 'а' && r <= 'я') || (r >= 'А' && r <= 'Я') || r == 'ё' || r == 'Ё' {
-			return true
-		}
-	}
-	return false
-}
-
 // isPunctuation checks if a token is a punctuation mark
 func isPunctuation(s string) bool {
 	if len(s) == 0 {
@@ -245,17 +405,101 @@ func isSentenceEnd(s string) bool {
 	return strings.ContainsRune(endPunctuation, rune(s[len(s)-1]))
 }
 
+// Clear drops the chains of the chat, including the per-topic ones
 func (s *Service) Clear(chatID int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.chains, chatID)
+	for _, key := range s.chains.keys() {
+		if key.ChatID == chatID {
+			s.chains.remove(key)
+		}
+	}
+	for key, p := range s.loading {
+		if key.ChatID == chatID {
+			p.cleared = true
+			delete(s.loading, key)
+		}
+	}
+	s.evict()
+}
+
+// Ensure loads the chain unless it is already in memory
+func (s *Service) Ensure(ctx context.Context, chatID, threadID int64) error {
+	key := chainKey{ChatID: chatID, ThreadID: threadID}
+
+	s.mu.Lock()
+	_, exists := s.chains.get(key)
+	s.mu.Unlock()
+
+	if exists {
+		chainHits.Add(1)
+		return nil
+	}
+
+	chainMisses.Add(1)
+
+	// Leave the database to the warm-up until it is over
+	if !s.ready.Load() {
+		return ErrWarmingUp
+	}
+
+	return s.Load(ctx, chatID, threadID)
+}
+
+// pendingLoad is a chain being loaded, done is closed when it is over and
+// err is the error it failed with then
+type pendingLoad struct {
+	done chan struct{}
+	err  error
+	// cleared is set when the chat is cleared during the load, the chain
+	// may have been trained on deleted messages and is thrown away
+	cleared bool
 }
 
-// Load loads messages for a chat and trains the Markov chain with recency weighting
-func (s *Service) Load(ctx context.Context, chatID int64) error {
+// beginLoad marks the chain as being loaded. If it already is, it returns
+// the pending load and false.
+func (s *Service) beginLoad(key chainKey) (*pendingLoad, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, exists := s.loading[key]; exists {
+		return p, false
+	}
+
+	p := &pendingLoad{done: make(chan struct{})}
+	s.loading[key] = p
+	return p, true
+}
+
+// Load loads messages for a chat, or for a topic of it if threadID is not
+// zero, and trains a new Markov chain with recency weighting replacing the
+// one in memory. Loads of the chain waiting for one already running fail
+// with its error.
+func (s *Service) Load(ctx context.Context, chatID, threadID int64) (err error) {
+	key := chainKey{ChatID: chatID, ThreadID: threadID}
+
+	pending, first := s.beginLoad(key)
+	if !first {
+		select {
+		case <-pending.done:
+			return pending.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		s.mu.Lock()
+		if s.loading[key] == pending {
+			delete(s.loading, key)
+		}
+		s.mu.Unlock()
+		pending.err = err
+		close(pending.done)
+	}()
+
 	// Get recent messages first
-	recentMsgs, err := s.repo.GetByChatID(ctx, chatID, 100, 0) // Last 100 messages
+	recentMsgs, err := s.messages(ctx, key, 100, 0) // Last 100 messages
 	if err != nil {
 		return fmt.Errorf("failed to get recent messages: %w", err)
 	}
@@ -263,24 +507,31 @@ func (s *Service) Load(ctx context.Context, chatID int64) error {
 	// Get older messages if needed
 	var olderMsgs []*entity.Message
 	if len(recentMsgs) < 50 { // If we have less than 50 recent messages
-		olderMsgs, _ = s.repo.GetByChatID(ctx, chatID, 1000, 100) // Next 1000 messages
+		olderMsgs, _ = s.messages(ctx, key, 1000, 100) // Next 1000 messages
 	}
 
 	allMessages := append(recentMsgs, olderMsgs...)
 	s.logg.Info("loading chat",
 		zap.Int64("chat_id", chatID),
+		zap.Int64("thread_id", threadID),
 		zap.Int("recent_messages", len(recentMsgs)),
 		zap.Int("older_messages", len(olderMsgs)),
 	)
 
-	chain := s.getOrCreateChain(chatID)
-	s.mu.Lock()
-	s.recentMessages[chatID] = make([]string, 0, 20)
-	s.mu.Unlock()
+	e := &entry{
+		key:    key,
+		chain:  gomarkov.NewChain(s.opts.Order),
+		recent: make([]string, 0, 20),
+	}
+	chain := e.chain
 
 	// Train with higher weight for recent messages
 	for i, msg := range allMessages {
 		tokens := strings.Fields(msg.Text)
+		// Messages are newest first, the one sent before is the next one
+		if isMediaMessage(tokens) && i+1 < len(allMessages) {
+			tokens = mediaTokens(allMessages[i+1].Text, tokens[0], chain.Order)
+		}
 		if len(tokens) > 1 {
 			// Higher weight for recent messages
 			weight := 1.0
@@ -288,149 +539,222 @@ func (s *Service) Load(ctx context.Context, chatID int64) error {
 				weight = 2.0
 			}
 			trainWithWeight(chain, tokens, weight)
+			e.tokens += len(tokens)
 
 			// Store recent messages for context
 			if i < 20 {
-				s.mu.Lock()
-				s.recentMessages[chatID] = append(s.recentMessages[chatID], msg.Text)
-				s.mu.Unlock()
+				e.recent = append(e.recent, msg.Text)
 			}
 		}
 	}
 
+	// Recent messages are kept oldest first
+	slices.Reverse(e.recent)
+
+	e.tokens += s.trainQuotes(ctx, key, chain)
+
+	texts := make([]string, 0, len(allMessages))
+	for _, msg := range allMessages {
+		if !isMediaMessage(strings.Fields(msg.Text)) {
+			texts = append(texts, msg.Text)
+		}
+	}
+	e.detected = detect(texts)
+	e.feedback = s.loadFeedback(ctx, key, chain.Order)
+
+	s.mu.Lock()
+	if !pending.cleared {
+		s.chains.put(e)
+		s.evict()
+	}
+	s.mu.Unlock()
+
 	// Log chain statistics
-	s.logChainStats(chatID, chain)
+	s.logChainStats(key)
 
 	return nil
 }
 
+// trainQuotes trains the chain on the quote book of the chat, or of the
+// topic, and returns the number of tokens trained on
+func (s *Service) trainQuotes(ctx context.Context, key chainKey, chain *gomarkov.Chain) int {
+	if s.quotes == nil {
+		return 0
+	}
+
+	texts, err := s.quotes.GetTexts(ctx, key.ChatID, key.ThreadID, maxQuotes)
+	if err != nil {
+		s.logg.Warn("failed to load quotes",
+			zap.Int64("chat_id", key.ChatID),
+			zap.Int64("thread_id", key.ThreadID),
+			zap.Error(err),
+		)
+		return 0
+	}
+
+	trained := 0
+	for _, text := range texts {
+		tokens := strings.Fields(text)
+		if len(tokens) > 1 {
+			trainWithWeight(chain, tokens, quoteWeight)
+			trained += len(tokens)
+		}
+	}
+	return trained
+}
+
 // logChainStats logs statistics about a Markov chain
-func (s *Service) logChainStats(chatID int64, chain *gomarkov.Chain) {
-	stats := s.GetChainStats(chatID)
+func (s *Service) logChainStats(key chainKey) {
+	stats := s.getChainStats(key)
 	s.logg.Info("chain statistics",
-		zap.Int64("chat_id", chatID),
+		zap.Int64("chat_id", key.ChatID),
+		zap.Int64("thread_id", key.ThreadID),
 		zap.Int("order", stats.Order),
-		zap.Int("total_tokens", stats.TotalTokens),
-		zap.Int("unique_ngrams", stats.UniqueNGrams),
+		zap.Int("states", stats.States),
+		zap.Int("vocabulary", stats.Vocabulary),
+		zap.Int("transitions", stats.Transitions),
 	)
 }
 
-// GetChainStats returns statistics about a Markov chain
-func (s *Service) GetChainStats(chatID int64) ChainStats {
-	chain := s.getChain(chatID)
-	if chain == nil {
-		return ChainStats{}
-	}
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Use reflection to get unexported fields
-	// This is a bit hacky but works for getting stats
-	chainValue := reflect.ValueOf(chain).Elem()
-	order := int(chainValue.FieldByName("Order").Int())
-	freqs := chainValue.FieldByName("Freq")
-
-	// Count total tokens and unique n-grams
-	totalTokens := 0
-	uniqueNGrams := 0
+// Language returns the language detected in the chat, or in the topic if
+// threadID is not zero. It is empty if the chain isn't loaded or the
+// messages have no letters.
+func (s *Service) Language(chatID, threadID int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if freqs.IsValid() {
-		for _, key := range freqs.MapKeys() {
-			count := int(freqs.MapIndex(key).Int())
-			totalTokens += count
-			uniqueNGrams++
-		}
+	e, exists := s.chains.peek(chainKey{ChatID: chatID, ThreadID: threadID})
+	if !exists {
+		return ""
 	}
+	return e.detected.language
+}
 
-	return ChainStats{
-		Order:        order,
-		TotalTokens:  totalTokens,
-		UniqueNGrams: uniqueNGrams,
-	}
+// GetChainStats returns statistics about the Markov chain of a whole chat
+func (s *Service) GetChainStats(chatID int64) ChainStats {
+	return s.getChainStats(chainKey{ChatID: chatID})
 }
 
-// LoadAllChats loads messages for all available chats
-func (s *Service) LoadAllChats(ctx context.Context) error {
-	chatIDs, err := s.repo.GetAllChatIDs(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get chat IDs: %w", err)
+func (s *Service) getChainStats(key chainKey) ChainStats {
+	s.mu.Lock()
+	e, exists := s.chains.peek(key)
+	s.mu.Unlock()
+	if !exists {
+		return ChainStats{}
 	}
 
-	s.logg.Info("loading all chats", zap.Int("total_chats", len(chatIDs)))
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(chatIDs))
+	e.mu.RLock()
+	chain := e.chain
+	data, err := chain.MarshalJSON()
+	e.mu.RUnlock()
+	if err != nil {
+		s.logg.Error("failed to encode chain", zap.Error(err))
+		return ChainStats{Order: chain.Order}
+	}
 
-	for _, chatID := range chatIDs {
-		wg.Add(1)
-		go func(id int64) {
-			defer wg.Done()
-			if err := s.Load(ctx, id); err != nil {
-				errChan <- fmt.Errorf("failed to load chat %d: %w", id, err)
-			}
-		}(chatID)
+	var decoded chainJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		s.logg.Error("failed to decode chain", zap.Error(err))
+		return ChainStats{Order: chain.Order}
 	}
 
-	// Close the error channel when all goroutines are done
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
+	stats := ChainStats{Order: decoded.Order, States: len(decoded.FreqMat)}
 
-	// Collect all errors
-	var errors []error
-	for err := range errChan {
-		errors = append(errors, err)
+	tokens := make(map[int]struct{})
+	for _, next := range decoded.FreqMat {
+		for token, count := range next {
+			tokens[token] = struct{}{}
+			stats.Transitions += count
+		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("encountered %d errors while loading chats, first error: %w", len(errors), errors[0])
+	// The end token is a transition target but not a word
+	stats.Vocabulary = len(tokens)
+	if stats.Vocabulary > 0 {
+		stats.Vocabulary--
 	}
 
-	return nil
+	return stats
 }
 
-func (s *Service) getOrCreateChain(chatID int64) *gomarkov.Chain {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// messages returns messages of the chat, or of a single topic of it
+func (s *Service) messages(ctx context.Context, key chainKey, limit, offset int) ([]*entity.Message, error) {
+	if key.ThreadID != 0 {
+		return s.repo.GetByThread(ctx, key.ChatID, key.ThreadID, limit, offset)
+	}
+	return s.repo.GetByChatID(ctx, key.ChatID, limit, offset)
+}
 
-	chain, exists := s.chains[chatID]
-	if !exists {
-		chain = gomarkov.NewChain(s.order)
-		s.chains[chatID] = chain
+// evict drops the least recently used chains over the memory budget,
+// s.mu must be held
+func (s *Service) evict() {
+	if n := s.chains.evict(s.opts.MaxChains, s.opts.MaxTokens); n > 0 {
+		chainEvictions.Add(int64(n))
 	}
 
-	return chain
+	chainsLoaded.Set(int64(s.chains.len()))
+	chainTokens.Set(int64(s.chains.tokens))
 }
 
-func (s *Service) getChain(chatID int64) *gomarkov.Chain {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// lockedRand draws from the global source of math/rand, which is safe for
+// concurrent use unlike the one gomarkov.Chain.Generate shares
+type lockedRand struct{}
 
-	return s.chains[chatID]
+func (lockedRand) Intn(n int) int {
+	return rand.Intn(n)
 }
 
-func (s *Service) getRandomToken(chatID int64) (string, error) {
-	chain := s.getChain(chatID)
-	if chain == nil {
-		return "", fmt.Errorf("no chain available")
+var prng gomarkov.PRNG = lockedRand{}
+
+// seedState returns a start state ending with one of the words that the
+// chain has seen at the beginning of a sequence, nil if there is none
+func seedState(chain *gomarkov.Chain, words []string) gomarkov.NGram {
+	for _, i := range rand.Perm(len(words)) {
+		state := make(gomarkov.NGram, chain.Order)
+		for j := 0; j < chain.Order-1; j++ {
+			state[j] = gomarkov.StartToken
+		}
+		state[chain.Order-1] = words[i]
+
+		if _, err := chain.GenerateDeterministic(state, prng); err == nil {
+			return state
+		}
 	}
 
+	return nil
+}
+
+// getRandomToken draws a word the chain has seen at the beginning of a
+// sequence, written in one of the scripts. It returns ErrNoData if no draw
+// finds one.
+func (s *Service) getRandomToken(chain *gomarkov.Chain, allowed []*unicode.RangeTable) (string, error) {
 	startState := make(gomarkov.NGram, chain.Order)
 	for i := 0; i < chain.Order; i++ {
 		startState[i] = gomarkov.StartToken
 	}
 
-	token, err := chain.Generate(startState)
-	if err != nil {
-		s.logg.Error("failed to generate token", zap.Error(err))
-		return "", fmt.Errorf("failed to generate token: %w", err)
-	}
+	for range maxStartDraws {
+		token, err := chain.GenerateDeterministic(startState, prng)
+		if err != nil {
+			s.logg.Error("failed to generate token", zap.Error(err))
+			return "", fmt.Errorf("failed to generate token: %w", err)
+		}
 
-	if token == gomarkov.EndToken {
-		return s.getRandomToken(chatID)
+		if token != gomarkov.EndToken && isWord(token, allowed) {
+			return token, nil
+		}
 	}
 
-	return token, nil
+	return "", ErrNoData
+}
+
+// filterWords returns the tokens that are words of the scripts
+func filterWords(tokens []string, allowed []*unicode.RangeTable) []string {
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if isWord(token, allowed) {
+			words = append(words, token)
+		}
+	}
+	return words
 }
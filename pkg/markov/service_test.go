@@ -0,0 +1,165 @@
+package markov
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"github.com/malinatrash/egonez/internal/entity"
+	"github.com/malinatrash/egonez/internal/ports"
+)
+
+// fakeMessages serves the same messages for every chat and topic, or fails
+// with err after waiting for release
+type fakeMessages struct {
+	ports.MessageRepository
+
+	texts   []string
+	err     error
+	release chan struct{}
+	calls   atomic.Int64
+}
+
+func (r *fakeMessages) GetByChatID(ctx context.Context, chatID int64, limit, offset int) ([]*entity.Message, error) {
+	r.calls.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var messages []*entity.Message
+	for i := offset; i < len(r.texts) && i < offset+limit; i++ {
+		messages = append(messages, &entity.Message{ChatID: chatID, Text: r.texts[i]})
+	}
+	return messages, nil
+}
+
+func (r *fakeMessages) GetByThread(ctx context.Context, chatID, threadID int64, limit, offset int) ([]*entity.Message, error) {
+	return r.GetByChatID(ctx, chatID, limit, offset)
+}
+
+func newTestService(repo ports.MessageRepository) *Service {
+	return NewService(Options{Order: 2, MaxChains: 3}, repo, nil, nil, zap.NewNop())
+}
+
+// TestServiceConcurrent is meant to be run with -race
+func TestServiceConcurrent(t *testing.T) {
+	repo := &fakeMessages{texts: []string{
+		"the quick brown fox jumps over the lazy dog",
+		"the lazy dog sleeps all day long",
+		"a quick brown cat jumps over the fence",
+	}}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	const (
+		workers    = 8
+		iterations = 50
+		chats      = 5
+	)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				chatID := int64((w + i) % chats)
+				threadID := int64(i % 2)
+
+				switch (w + i) % 5 {
+				case 0:
+					if err := svc.Load(ctx, chatID, threadID); err != nil {
+						t.Errorf("Load: %v", err)
+					}
+				case 1:
+					if err := svc.Ensure(ctx, chatID, threadID); err != nil {
+						t.Errorf("Ensure: %v", err)
+					}
+				case 2:
+					if err := svc.Train(chatID, threadID, fmt.Sprintf("message %d from worker %d", i, w)); err != nil {
+						t.Errorf("Train: %v", err)
+					}
+				case 3:
+					if _, err := svc.Generate(chatID, threadID, "", 10, ""); err != nil && !errors.Is(err, ErrNoData) {
+						t.Errorf("Generate: %v", err)
+					}
+				case 4:
+					svc.Clear(chatID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoadWaitersGetError(t *testing.T) {
+	repo := &fakeMessages{
+		err:     errors.New("database is down"),
+		release: make(chan struct{}),
+	}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	errs := make(chan error, 2)
+	go func() { errs <- svc.Load(ctx, 1, 0) }()
+
+	// Let the second load find the first one pending
+	for repo.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() { errs <- svc.Load(ctx, 1, 0) }()
+	time.Sleep(10 * time.Millisecond)
+	close(repo.release)
+
+	for range 2 {
+		if err := <-errs; err == nil {
+			t.Error("Load succeeded, want the error of the first load")
+		}
+	}
+	if calls := repo.calls.Load(); calls != 1 {
+		t.Errorf("repository called %d times, want 1", calls)
+	}
+}
+
+func TestGenerateKeepsLanguage(t *testing.T) {
+	repo := &fakeMessages{texts: []string{
+		"привет как дела у тебя сегодня",
+		"все хорошо спасибо а у тебя",
+		"hello how are you today my friend",
+		"fine thanks and how are you",
+	}}
+	svc := newTestService(repo)
+	ctx := context.Background()
+
+	if err := svc.Load(ctx, 1, 0); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// The recent context is Russian, the seed half Russian
+	if err := svc.Train(1, 0, "ну и как там погода"); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	for _, prefix := range []string{"", "привет hello", "погода"} {
+		for range 50 {
+			text, err := svc.Generate(1, 0, prefix, 10, "en")
+			if err != nil {
+				t.Fatalf("Generate(%q): %v", prefix, err)
+			}
+			for _, r := range text {
+				if unicode.Is(unicode.Cyrillic, r) {
+					t.Fatalf("Generate(%q) = %q, want no Cyrillic", prefix, text)
+				}
+			}
+		}
+	}
+}
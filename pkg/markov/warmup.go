@@ -0,0 +1,183 @@
+package markov
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// warmUpWindow is the period chat activity is measured over for warm-up
+const warmUpWindow = 30 * 24 * time.Hour
+
+// progressEvery is how many loaded chats are reported at once
+const progressEvery = 10
+
+var (
+	warmUpTotal  = expvar.NewInt("markov_warmup_chats_total")
+	warmUpDone   = expvar.NewInt("markov_warmup_chats_done")
+	warmUpFailed = expvar.NewInt("markov_warmup_chats_failed")
+	readyGauge   = expvar.NewInt("markov_ready")
+)
+
+// WarmUp loads the chats with the most messages over the last month using
+// a pool of workers. It stops early when the context is done, and marks the
+// service as ready when it returns either way.
+func (s *Service) WarmUp(ctx context.Context, chats int) error {
+	defer s.setReady()
+
+	var chatIDs []int64
+	err := s.withRetry(ctx, func() error {
+		var err error
+		chatIDs, err = s.repo.GetActiveChatIDs(ctx, time.Now().Add(-warmUpWindow), chats)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get chat IDs: %w", err)
+	}
+
+	workers := max(s.opts.WarmUpWorkers, 1)
+	warmUpTotal.Set(int64(len(chatIDs)))
+	s.logg.Info("warming up chats", zap.Int("total_chats", len(chatIDs)), zap.Int("workers", workers))
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		done  atomic.Int64
+		jobs  = make(chan int64)
+		start = time.Now()
+		total = int64(len(chatIDs))
+	)
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				err := s.withRetry(ctx, func() error {
+					return s.Load(ctx, id, 0)
+				})
+				if err != nil {
+					warmUpFailed.Add(1)
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to load chat %d: %w", id, err))
+					mu.Unlock()
+				}
+
+				warmUpDone.Add(1)
+				if n := done.Add(1); n%progressEvery == 0 || n == total {
+					s.logg.Info("warm-up progress",
+						zap.Int64("done", n),
+						zap.Int64("total", total),
+						zap.Duration("elapsed", time.Since(start)),
+					)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, id := range chatIDs {
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("warm-up interrupted after %d of %d chats: %w", done.Load(), total, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to load %d of %d chats: %w", len(errs), total, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (s *Service) setReady() {
+	s.ready.Store(true)
+	readyGauge.Set(1)
+}
+
+// Ready reports whether the warm-up is over
+func (s *Service) Ready() bool {
+	return s.ready.Load()
+}
+
+// withRetry runs op, retrying it with exponential backoff while it fails
+// with a transient error
+func (s *Service) withRetry(ctx context.Context, op func() error) error {
+	backoff := s.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= s.opts.Retries || !isTransient(err) {
+			return err
+		}
+
+		s.logg.Warn("transient error, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether the error is worth retrying: a broken or
+// refused connection, a timeout, or a Postgres error of the connection
+// exception class or one telling to try again later
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// Postgres errors expose their SQLSTATE code as the 'C' field
+	var pgErr interface{ Field(byte) string }
+	if errors.As(err, &pgErr) {
+		code := pgErr.Field('C')
+		switch {
+		case strings.HasPrefix(code, "08"), // connection exception
+			code == "40001", // serialization failure
+			code == "40P01", // deadlock detected
+			code == "53300", // too many connections
+			code == "57P03": // cannot connect now
+			return true
+		}
+	}
+
+	return false
+}